@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+	"github.com/snowmerak/renovates/lib/runner"
+	"github.com/snowmerak/renovates/lib/schedule"
+)
+
+// runDaemon runs discovery and Renovate on the configured cron schedule until
+// ctx is cancelled. Runs never overlap: the next run is scheduled only after
+// the previous one has finished.
+func runDaemon(ctx context.Context, cfg *renovate.Config, r *runner.Runner) error {
+	if cfg.Daemon.Schedule == "" {
+		return fmt.Errorf("daemon mode requires [daemon] schedule in config")
+	}
+	if !cfg.Discovery.Enabled {
+		return fmt.Errorf("daemon mode requires discovery to be enabled")
+	}
+
+	sched, err := schedule.Parse(cfg.Daemon.Schedule)
+	if err != nil {
+		return fmt.Errorf("failed to parse schedule: %w", err)
+	}
+
+	loc := time.Local
+	if cfg.Daemon.Timezone != "" {
+		loc, err = time.LoadLocation(cfg.Daemon.Timezone)
+		if err != nil {
+			return fmt.Errorf("failed to load timezone: %w", err)
+		}
+	}
+
+	if cfg.Daemon.RunOnStart {
+		runOnce(ctx, r)
+	}
+
+	for {
+		next := sched.Next(time.Now().In(loc))
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never fires", cfg.Daemon.Schedule)
+		}
+		if jitter := time.Duration(cfg.Daemon.Jitter); jitter > 0 {
+			next = next.Add(rand.N(jitter))
+		}
+
+		fmt.Printf("Next run scheduled at %s\n", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		runOnce(ctx, r)
+	}
+}
+
+func runOnce(ctx context.Context, r *runner.Runner) {
+	fmt.Println("Discovering repositories...")
+	targets, err := r.Discover(ctx)
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+	fmt.Printf("Found %d repositories: %v\n", len(targets), targets)
+
+	r.Run(ctx, targets)
+}
@@ -0,0 +1,110 @@
+package discovery
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+type azureRepository struct {
+	Name          string `json:"name"`
+	IsDisabled    bool   `json:"isDisabled"`
+	IsFork        bool   `json:"isFork"`
+	DefaultBranch string `json:"defaultBranch"`
+	Project       struct {
+		Name       string `json:"name"`
+		Visibility string `json:"visibility"`
+	} `json:"project"`
+}
+
+type azureRepositoryList struct {
+	Value []azureRepository `json:"value"`
+}
+
+// AzureDiscoverer lists Git repositories of an Azure DevOps organization.
+// The endpoint is the organization URL; the discovery owner optionally
+// restricts the listing to a single project.
+type AzureDiscoverer struct {
+	client  *http.Client
+	baseURL string
+	cfg     *renovate.Config
+	filter  *filter
+}
+
+func NewAzureDiscoverer(cfg *renovate.Config) (*AzureDiscoverer, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required for azure discovery")
+	}
+	// Azure Repos has no repository topics, languages or push dates in its
+	// repository list
+	f := cfg.Discovery.DiscoveryFilters
+	if len(f.Topics) > 0 || len(f.Languages) > 0 || f.PushedWithinDays > 0 {
+		return nil, fmt.Errorf("topics, languages and pushed_within_days are not supported for azure discovery")
+	}
+
+	flt, err := newFilter(cfg.Discovery.DiscoveryFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureDiscoverer{
+		client:  client,
+		baseURL: strings.TrimSuffix(cfg.Endpoint, "/"),
+		cfg:     cfg,
+		filter:  flt,
+	}, nil
+}
+
+func (d *AzureDiscoverer) ListRepositories(ctx context.Context) ([]string, error) {
+	u := d.baseURL
+	if d.cfg.Discovery.Owner != "" {
+		u += "/" + url.PathEscape(d.cfg.Discovery.Owner)
+	}
+	u += "/_apis/git/repositories?api-version=7.0"
+
+	header := http.Header{}
+	if d.cfg.Token != "" {
+		// Personal access tokens use basic auth with an empty user name
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(":"+d.cfg.Token)))
+	}
+
+	// The repositories endpoint is not paginated
+	var list azureRepositoryList
+	if _, err := getJSON(ctx, d.client, u, header, &list); err != nil {
+		return nil, err
+	}
+
+	var allRepos []string
+	for _, repo := range list.Value {
+		// Disabled repositories cannot be cloned
+		if repo.IsDisabled {
+			continue
+		}
+		if d.match(repo) {
+			allRepos = append(allRepos, repo.Project.Name+"/"+repo.Name)
+		}
+	}
+
+	return allRepos, nil
+}
+
+func (d *AzureDiscoverer) match(repo azureRepository) bool {
+	return d.filter.match(repository{
+		Name:     repo.Name,
+		FullName: repo.Project.Name + "/" + repo.Name,
+		Fork:     repo.IsFork,
+		// Visibility is set per project
+		Visibility: repo.Project.Visibility,
+		Empty:      repo.DefaultBranch == "",
+	})
+}
@@ -0,0 +1,123 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+const bitbucketPageSize = 100
+
+type bitbucketRepository struct {
+	Slug     string `json:"slug"`
+	Public   bool   `json:"public"`
+	Archived bool   `json:"archived"`
+	Origin   *struct {
+		Slug string `json:"slug"`
+	} `json:"origin"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+}
+
+type bitbucketPage struct {
+	Values        []bitbucketRepository `json:"values"`
+	IsLastPage    bool                  `json:"isLastPage"`
+	NextPageStart int                   `json:"nextPageStart"`
+}
+
+// BitbucketServerDiscoverer lists repositories from Bitbucket Server and
+// Data Center. The discovery owner is a project key.
+type BitbucketServerDiscoverer struct {
+	client  *http.Client
+	baseURL string
+	cfg     *renovate.Config
+	filter  *filter
+}
+
+func NewBitbucketServerDiscoverer(cfg *renovate.Config) (*BitbucketServerDiscoverer, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required for bitbucket-server discovery")
+	}
+	// Bitbucket Server has no repository topics, languages or push dates in
+	// its repository list
+	f := cfg.Discovery.DiscoveryFilters
+	if len(f.Topics) > 0 || len(f.Languages) > 0 || f.PushedWithinDays > 0 || f.SkipEmpty {
+		return nil, fmt.Errorf("topics, languages, pushed_within_days and skip_empty are not supported for bitbucket-server discovery")
+	}
+
+	baseURL := strings.TrimSuffix(cfg.Endpoint, "/")
+	baseURL = strings.TrimSuffix(baseURL, "/rest/api/1.0")
+
+	flt, err := newFilter(cfg.Discovery.DiscoveryFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BitbucketServerDiscoverer{
+		client:  client,
+		baseURL: baseURL + "/rest/api/1.0",
+		cfg:     cfg,
+		filter:  flt,
+	}, nil
+}
+
+func (d *BitbucketServerDiscoverer) ListRepositories(ctx context.Context) ([]string, error) {
+	path := "/repos"
+	if d.cfg.Discovery.Owner != "" {
+		path = "/projects/" + url.PathEscape(d.cfg.Discovery.Owner) + "/repos"
+	}
+
+	header := http.Header{}
+	if d.cfg.Token != "" {
+		header.Set("Authorization", "Bearer "+d.cfg.Token)
+	}
+
+	var allRepos []string
+	start := 0
+	for {
+		u := fmt.Sprintf("%s%s?start=%d&limit=%d", d.baseURL, path, start, bitbucketPageSize)
+
+		var page bitbucketPage
+		if _, err := getJSON(ctx, d.client, u, header, &page); err != nil {
+			return nil, err
+		}
+
+		for _, repo := range page.Values {
+			if d.match(repo) {
+				allRepos = append(allRepos, repo.Project.Key+"/"+repo.Slug)
+			}
+		}
+
+		if page.IsLastPage || len(page.Values) == 0 {
+			break
+		}
+		start = page.NextPageStart
+	}
+
+	return allRepos, nil
+}
+
+func (d *BitbucketServerDiscoverer) match(repo bitbucketRepository) bool {
+	visibility := "private"
+	if repo.Public {
+		visibility = "public"
+	}
+
+	return d.filter.match(repository{
+		Name:       repo.Slug,
+		FullName:   repo.Project.Key + "/" + repo.Slug,
+		Archived:   repo.Archived,
+		Fork:       repo.Origin != nil,
+		Visibility: visibility,
+	})
+}
@@ -0,0 +1,202 @@
+package discovery
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+const defaultCacheDir = ".renovates-cache"
+
+// cacheEntry is a cached GET response.
+type cacheEntry struct {
+	URL      string      `json:"url"`
+	StoredAt time.Time   `json:"storedAt"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+}
+
+// cachingTransport caches successful GET responses on disk. Responses younger
+// than the TTL are served without a request; older ones are revalidated with
+// their ETag or Last-Modified, so unchanged lists cost a 304 instead of a
+// full response (and, on GitHub, no rate limit).
+type cachingTransport struct {
+	base  http.RoundTripper
+	dir   string
+	ttl   time.Duration
+	scope string
+}
+
+// newHTTPClient returns the HTTP client discoverers use for cfg, caching
+// responses when enabled.
+func newHTTPClient(cfg *renovate.Config) (*http.Client, error) {
+	cache := cfg.Discovery.Cache
+	if !cache.Enabled {
+		return http.DefaultClient, nil
+	}
+
+	dir := cache.Path
+	if dir == "" {
+		dir = defaultCacheDir
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create discovery cache: %w", err)
+	}
+
+	// Responses depend on who is asking; App tokens rotate, so key by the
+	// installation rather than the token.
+	scope := cfg.Token
+	if cfg.GitHubApp.AppID != 0 {
+		scope = fmt.Sprintf("app:%d:%d", cfg.GitHubApp.AppID, cfg.GitHubApp.InstallationID)
+	}
+
+	return &http.Client{
+		Transport: &cachingTransport{
+			base:  http.DefaultTransport,
+			dir:   dir,
+			ttl:   time.Duration(cache.TTL),
+			scope: scope,
+		},
+	}, nil
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	path := t.path(req)
+	entry, err := t.load(path)
+	if err != nil {
+		log.Printf("ignoring discovery cache entry for %s: %v", req.URL, err)
+		entry = nil
+	}
+
+	if entry != nil && time.Since(entry.StoredAt) < t.ttl {
+		return entry.response(req), nil
+	}
+
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := entry.Header.Get("Last-Modified"); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		entry.StoredAt = time.Now()
+		t.store(path, entry)
+		return entry.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Nothing to revalidate with, and a TTL of zero would never reuse it
+	if t.ttl > 0 || resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "" {
+		t.store(path, &cacheEntry{
+			URL:      req.URL.String(),
+			StoredAt: time.Now(),
+			Header:   resp.Header,
+			Body:     body,
+		})
+	}
+
+	return resp, nil
+}
+
+func (t *cachingTransport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(t.scope + "\n" + req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *cachingTransport) load(path string) (*cacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// store writes entry atomically; failures only cost a later cache miss.
+func (t *cachingTransport) store(path string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("failed to encode discovery cache entry: %v", err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(t.dir, "*.tmp")
+	if err != nil {
+		log.Printf("failed to write discovery cache: %v", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		log.Printf("failed to write discovery cache: %v", err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		log.Printf("failed to write discovery cache: %v", err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		log.Printf("failed to write discovery cache: %v", err)
+	}
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	// Same marker as common HTTP caches; go-github skips rate limit
+	// bookkeeping for such responses.
+	header.Set("X-From-Cache", "1")
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
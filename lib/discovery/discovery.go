@@ -2,9 +2,9 @@ package discovery
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strings"
 
 	"github.com/google/go-github/v57/github"
@@ -18,11 +18,21 @@ type Discoverer interface {
 }
 
 func NewDiscoverer(cfg *renovate.Config) (Discoverer, error) {
+	if len(cfg.Discovery.Manifests) > 0 && cfg.Platform != "github" {
+		return nil, fmt.Errorf("manifests are only supported for github discovery")
+	}
+
 	switch cfg.Platform {
 	case "github":
-		return NewGitHubDiscoverer(cfg), nil
+		return NewGitHubDiscoverer(cfg)
 	case "gitlab":
 		return NewGitLabDiscoverer(cfg)
+	case "gitea":
+		return NewGiteaDiscoverer(cfg)
+	case "bitbucket-server":
+		return NewBitbucketServerDiscoverer(cfg)
+	case "azure":
+		return NewAzureDiscoverer(cfg)
 	default:
 		return nil, fmt.Errorf("unsupported platform for discovery: %s", cfg.Platform)
 	}
@@ -33,14 +43,25 @@ func NewDiscoverer(cfg *renovate.Config) (Discoverer, error) {
 type GitHubDiscoverer struct {
 	client *github.Client
 	cfg    *renovate.Config
+	filter *filter
 }
 
-func NewGitHubDiscoverer(cfg *renovate.Config) *GitHubDiscoverer {
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: cfg.Token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
+func NewGitHubDiscoverer(cfg *renovate.Config) (*GitHubDiscoverer, error) {
+	flt, err := newFilter(cfg.Discovery.DiscoveryFilters)
+	if err != nil {
+		return nil, err
+	}
+	if flt.ManifestLookup == manifestLookupSearch && cfg.Discovery.Owner == "" {
+		return nil, fmt.Errorf("manifest_lookup = %q requires a discovery owner", manifestLookupSearch)
+	}
+
+	hc, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, hc)
+	tc := oauth2.NewClient(ctx, cfg.TokenSource())
 
 	var client *github.Client
 	if cfg.Endpoint != "" && cfg.Endpoint != "https://api.github.com" {
@@ -50,11 +71,11 @@ func NewGitHubDiscoverer(cfg *renovate.Config) *GitHubDiscoverer {
 		client = github.NewClient(tc)
 	}
 
-	return &GitHubDiscoverer{client: client, cfg: cfg}
+	return &GitHubDiscoverer{client: client, cfg: cfg, filter: flt}, nil
 }
 
 func (d *GitHubDiscoverer) ListRepositories(ctx context.Context) ([]string, error) {
-	var allRepos []string
+	var matched []*github.Repository
 	opt := &github.RepositoryListOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
@@ -68,6 +89,27 @@ func (d *GitHubDiscoverer) ListRepositories(ctx context.Context) ([]string, erro
 		user = d.cfg.Discovery.Owner
 	}
 
+	// Installation tokens have no user; list what the app is installed on
+	if user == "" && d.cfg.GitHubApp.AppID != 0 {
+		matched, err := d.listInstallationRepositories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return d.selectRepositories(ctx, matched)
+	}
+
+	if user != "" {
+		matched, err := d.listOrgRepositories(ctx, user)
+		if err == nil {
+			return d.selectRepositories(ctx, matched)
+		}
+		var errResp *github.ErrorResponse
+		if !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusNotFound {
+			return nil, err
+		}
+		// Not an organization; the user endpoint only lists public repositories
+	}
+
 	for {
 		repos, resp, err := d.client.Repositories.List(ctx, user, opt)
 		if err != nil {
@@ -76,7 +118,7 @@ func (d *GitHubDiscoverer) ListRepositories(ctx context.Context) ([]string, erro
 
 		for _, repo := range repos {
 			if d.match(repo) {
-				allRepos = append(allRepos, *repo.FullName)
+				matched = append(matched, repo)
 			}
 		}
 
@@ -86,49 +128,122 @@ func (d *GitHubDiscoverer) ListRepositories(ctx context.Context) ([]string, erro
 		opt.Page = resp.NextPage
 	}
 
-	return allRepos, nil
+	return d.selectRepositories(ctx, matched)
 }
 
-func (d *GitHubDiscoverer) match(repo *github.Repository) bool {
-	// Topic Filter
-	if len(d.cfg.Discovery.Topics) > 0 {
-		matched := false
-		for _, topic := range repo.Topics {
-			for _, target := range d.cfg.Discovery.Topics {
-				if topic == target {
-					matched = true
-					break
-				}
+// selectRepositories applies the filters that need a request per repository
+// to the repositories that passed the others.
+func (d *GitHubDiscoverer) selectRepositories(ctx context.Context, repos []*github.Repository) ([]string, error) {
+	if d.filter.SkipEmpty {
+		var nonEmpty []*github.Repository
+		for _, repo := range repos {
+			empty, err := d.isEmpty(ctx, repo)
+			if err != nil {
+				return nil, err
+			}
+			if !empty {
+				nonEmpty = append(nonEmpty, repo)
 			}
 		}
-		if !matched {
-			return false
+		repos = nonEmpty
+	}
+
+	return d.withManifests(ctx, repos)
+}
+
+// isEmpty reports whether a repository has no commits. GitHub reports a
+// default branch even for empty repositories and updates the size lazily, so
+// neither tells a freshly pushed repository from an empty one.
+func (d *GitHubDiscoverer) isEmpty(ctx context.Context, repo *github.Repository) (bool, error) {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	opt := &github.CommitsListOptions{ListOptions: github.ListOptions{PerPage: 1}}
+	_, resp, err := d.client.Repositories.ListCommits(ctx, owner, name, opt)
+	if err != nil {
+		// Empty repositories answer 409 Conflict
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			return true, nil
 		}
+		return false, fmt.Errorf("failed to list commits of %s: %w", repo.GetFullName(), err)
 	}
+	return false, nil
+}
 
-	// Regex Include
-	if len(d.cfg.Discovery.Includes) > 0 {
-		matched := false
-		for _, pattern := range d.cfg.Discovery.Includes {
-			if matched, _ = regexp.MatchString(pattern, *repo.Name); matched {
-				break
+// listOrgRepositories lists the repositories of an organization, including
+// private and internal ones the token can see.
+func (d *GitHubDiscoverer) listOrgRepositories(ctx context.Context, org string) ([]*github.Repository, error) {
+	var matched []*github.Repository
+	opt := &github.RepositoryListByOrgOptions{
+		Type:        "all",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	for {
+		repos, resp, err := d.client.Repositories.ListByOrg(ctx, org, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range repos {
+			if d.match(repo) {
+				matched = append(matched, repo)
 			}
 		}
-		if !matched {
-			return false
+
+		if resp.NextPage == 0 {
+			break
 		}
+		opt.Page = resp.NextPage
 	}
 
-	// Regex Exclude
-	if len(d.cfg.Discovery.Excludes) > 0 {
-		for _, pattern := range d.cfg.Discovery.Excludes {
-			if matched, _ := regexp.MatchString(pattern, *repo.Name); matched {
-				return false
+	return matched, nil
+}
+
+func (d *GitHubDiscoverer) listInstallationRepositories(ctx context.Context) ([]*github.Repository, error) {
+	var matched []*github.Repository
+	opt := &github.ListOptions{PerPage: 100}
+
+	for {
+		list, resp, err := d.client.Apps.ListRepos(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range list.Repositories {
+			if d.match(repo) {
+				matched = append(matched, repo)
 			}
 		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return matched, nil
+}
+
+func (d *GitHubDiscoverer) match(repo *github.Repository) bool {
+	visibility := repo.GetVisibility()
+	if visibility == "" {
+		visibility = "public"
+		if repo.GetPrivate() {
+			visibility = "private"
+		}
+	}
+
+	r := repository{
+		Name:       repo.GetName(),
+		FullName:   repo.GetFullName(),
+		Topics:     repo.Topics,
+		Archived:   repo.GetArchived(),
+		Fork:       repo.GetFork(),
+		Visibility: visibility,
+		PushedAt:   repo.GetPushedAt().Time,
 	}
 
-	return true
+	return d.filter.match(r) &&
+		d.filter.matchLanguage(repo.GetLanguage())
 }
 
 // --- GitLab ---
@@ -136,10 +251,21 @@ func (d *GitHubDiscoverer) match(repo *github.Repository) bool {
 type GitLabDiscoverer struct {
 	client *gitlab.Client
 	cfg    *renovate.Config
+	filter *filter
 }
 
 func NewGitLabDiscoverer(cfg *renovate.Config) (*GitLabDiscoverer, error) {
-	opts := []gitlab.ClientOptionFunc{}
+	flt, err := newFilter(cfg.Discovery.DiscoveryFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	hc, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []gitlab.ClientOptionFunc{gitlab.WithHTTPClient(hc)}
 	if cfg.Endpoint != "" {
 		opts = append(opts, gitlab.WithBaseURL(cfg.Endpoint))
 	}
@@ -149,7 +275,7 @@ func NewGitLabDiscoverer(cfg *renovate.Config) (*GitLabDiscoverer, error) {
 		return nil, err
 	}
 
-	return &GitLabDiscoverer{client: client, cfg: cfg}, nil
+	return &GitLabDiscoverer{client: client, cfg: cfg, filter: flt}, nil
 }
 
 func (d *GitLabDiscoverer) ListRepositories(ctx context.Context) ([]string, error) {
@@ -167,7 +293,10 @@ func (d *GitLabDiscoverer) ListRepositories(ctx context.Context) ([]string, erro
 	var allRepos []string
 	opt := &gitlab.ListProjectsOptions{
 		ListOptions: gitlab.ListOptions{PerPage: 100},
-		Simple:      gitlab.Ptr(true), // Get simple details to save bandwidth
+		Simple:      gitlab.Ptr(d.simple()), // Get simple details to save bandwidth
+	}
+	if d.cfg.Discovery.SkipArchived {
+		opt.Archived = gitlab.Ptr(false)
 	}
 
 	// If topics are provided, use them
@@ -182,7 +311,11 @@ func (d *GitLabDiscoverer) ListRepositories(ctx context.Context) ([]string, erro
 		}
 
 		for _, p := range projects {
-			if d.match(p) {
+			ok, err := d.match(ctx, p)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
 				allRepos = append(allRepos, p.PathWithNamespace)
 			}
 		}
@@ -200,9 +333,12 @@ func (d *GitLabDiscoverer) listGroupProjects(ctx context.Context, groupID int) (
 	var allRepos []string
 	opt := &gitlab.ListGroupProjectsOptions{
 		ListOptions:      gitlab.ListOptions{PerPage: 100},
-		Simple:           gitlab.Ptr(true),
+		Simple:           gitlab.Ptr(d.simple()),
 		IncludeSubGroups: gitlab.Ptr(true),
 	}
+	if d.cfg.Discovery.SkipArchived {
+		opt.Archived = gitlab.Ptr(false)
+	}
 
 	for {
 		projects, resp, err := d.client.Groups.ListGroupProjects(groupID, opt, gitlab.WithContext(ctx))
@@ -211,7 +347,11 @@ func (d *GitLabDiscoverer) listGroupProjects(ctx context.Context, groupID int) (
 		}
 
 		for _, p := range projects {
-			if d.match(p) {
+			ok, err := d.match(ctx, p)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
 				allRepos = append(allRepos, p.PathWithNamespace)
 			}
 		}
@@ -224,35 +364,45 @@ func (d *GitLabDiscoverer) listGroupProjects(ctx context.Context, groupID int) (
 	return allRepos, nil
 }
 
-func (d *GitLabDiscoverer) match(p *gitlab.Project) bool {
+func (d *GitLabDiscoverer) match(ctx context.Context, p *gitlab.Project) (bool, error) {
 	// Owner check (if not handled by API)
 	if d.cfg.Discovery.Owner != "" {
 		if !strings.HasPrefix(p.PathWithNamespace, d.cfg.Discovery.Owner+"/") {
-			return false
+			return false, nil
 		}
 	}
 
-	// Regex Include
-	if len(d.cfg.Discovery.Includes) > 0 {
-		matched := false
-		for _, pattern := range d.cfg.Discovery.Includes {
-			if matched, _ = regexp.MatchString(pattern, p.Name); matched {
-				break
-			}
-		}
-		if !matched {
-			return false
-		}
+	r := repository{
+		Name:       p.Name,
+		FullName:   p.PathWithNamespace,
+		Topics:     p.Topics,
+		Archived:   p.Archived,
+		Fork:       p.ForkedFromProject != nil,
+		Visibility: string(p.Visibility),
+		Empty:      p.EmptyRepo || p.DefaultBranch == "",
+	}
+	if p.LastActivityAt != nil {
+		r.PushedAt = *p.LastActivityAt
 	}
 
-	// Regex Exclude
-	if len(d.cfg.Discovery.Excludes) > 0 {
-		for _, pattern := range d.cfg.Discovery.Excludes {
-			if matched, _ := regexp.MatchString(pattern, p.Name); matched {
-				return false
-			}
-		}
+	if !d.filter.match(r) {
+		return false, nil
 	}
+	if len(d.filter.Languages) == 0 {
+		return true, nil
+	}
+
+	// The project list does not include languages
+	languages, _, err := d.client.Projects.GetProjectLanguages(p.ID, gitlab.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to get languages of %s: %w", p.PathWithNamespace, err)
+	}
+	return d.filter.matchLanguage(primaryLanguage(map[string]float32(*languages))), nil
+}
 
-	return true
+// simple reports whether the simple project view carries every attribute
+// the filters need.
+func (d *GitLabDiscoverer) simple() bool {
+	f := d.filter
+	return !f.SkipArchived && !f.SkipForks && !f.SkipEmpty && len(f.Visibility) == 0
 }
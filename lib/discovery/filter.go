@@ -0,0 +1,215 @@
+package discovery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+// repository holds the attributes discovery filters on, independently of the
+// platform it was listed from.
+type repository struct {
+	Name       string
+	FullName   string
+	Topics     []string
+	Archived   bool
+	Fork       bool
+	Visibility string
+	Empty      bool
+	PushedAt   time.Time
+}
+
+// pattern is a compiled include or exclude pattern.
+type pattern struct {
+	re *regexp.Regexp
+	// Patterns containing a slash match the full path instead of the name
+	fullPath bool
+}
+
+func (p pattern) match(repo repository) bool {
+	if p.fullPath {
+		return p.re.MatchString(repo.FullName)
+	}
+	return p.re.MatchString(repo.Name)
+}
+
+// compilePattern compiles a regular expression, or a glob when prefixed with
+// "glob:". In globs, "*" and "?" do not cross a slash and "**" does.
+func compilePattern(expr string) (pattern, error) {
+	src := expr
+	if glob, ok := strings.CutPrefix(expr, "glob:"); ok {
+		src = globToRegexp(glob)
+	}
+
+	re, err := regexp.Compile(src)
+	if err != nil {
+		return pattern{}, fmt.Errorf("invalid pattern %q: %w", expr, err)
+	}
+	return pattern{re: re, fullPath: strings.Contains(expr, "/")}, nil
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			// Character classes are passed through as is
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(glob[i:]))
+				i = len(glob)
+				continue
+			}
+			class := glob[i : i+end+1]
+			if strings.HasPrefix(class, "[!") {
+				class = "[^" + class[2:]
+			}
+			b.WriteString(class)
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// filter is the compiled form of the discovery filters.
+type filter struct {
+	renovate.DiscoveryFilters
+	includes []pattern
+	excludes []pattern
+}
+
+func newFilter(f renovate.DiscoveryFilters) (*filter, error) {
+	flt := &filter{DiscoveryFilters: f}
+
+	for _, expr := range f.Includes {
+		p, err := compilePattern(expr)
+		if err != nil {
+			return nil, fmt.Errorf("includes: %w", err)
+		}
+		flt.includes = append(flt.includes, p)
+	}
+	for _, expr := range f.Excludes {
+		p, err := compilePattern(expr)
+		if err != nil {
+			return nil, fmt.Errorf("excludes: %w", err)
+		}
+		flt.excludes = append(flt.excludes, p)
+	}
+
+	if f.PushedWithinDays < 0 {
+		return nil, fmt.Errorf("pushed_within_days must not be negative")
+	}
+
+	switch f.ManifestLookup {
+	case "", manifestLookupTree, manifestLookupSearch:
+	default:
+		return nil, fmt.Errorf("invalid manifest_lookup %q: expected %q or %q", f.ManifestLookup, manifestLookupTree, manifestLookupSearch)
+	}
+
+	return flt, nil
+}
+
+// match reports whether repo passes every filter except the language one,
+// which may need an extra request; see matchLanguage.
+func (f *filter) match(repo repository) bool {
+	// Topic Filter
+	if len(f.Topics) > 0 {
+		matched := false
+		for _, topic := range repo.Topics {
+			for _, target := range f.Topics {
+				if topic == target {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	// Pattern Include
+	if len(f.includes) > 0 {
+		matched := false
+		for _, p := range f.includes {
+			if matched = p.match(repo); matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	// Pattern Exclude
+	for _, p := range f.excludes {
+		if p.match(repo) {
+			return false
+		}
+	}
+
+	if f.SkipArchived && repo.Archived {
+		return false
+	}
+	if f.SkipForks && repo.Fork {
+		return false
+	}
+	if f.SkipEmpty && repo.Empty {
+		return false
+	}
+
+	if len(f.Visibility) > 0 && !containsFold(f.Visibility, repo.Visibility) {
+		return false
+	}
+
+	if f.PushedWithinDays > 0 {
+		since := time.Now().AddDate(0, 0, -f.PushedWithinDays)
+		if repo.PushedAt.IsZero() || repo.PushedAt.Before(since) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchLanguage reports whether language passes the language filter.
+func (f *filter) matchLanguage(language string) bool {
+	return len(f.Languages) == 0 || containsFold(f.Languages, language)
+}
+
+// primaryLanguage returns the language with the largest share.
+func primaryLanguage[T int | int64 | float32](languages map[string]T) string {
+	var primary string
+	var max T
+	for lang, size := range languages {
+		if size > max || (size == max && lang < primary) {
+			primary, max = lang, size
+		}
+	}
+	return primary
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
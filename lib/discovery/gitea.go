@@ -0,0 +1,159 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+const giteaPageSize = 50
+
+type giteaRepository struct {
+	Name      string    `json:"name"`
+	FullName  string    `json:"full_name"`
+	Topics    []string  `json:"topics"`
+	Archived  bool      `json:"archived"`
+	Fork      bool      `json:"fork"`
+	Private   bool      `json:"private"`
+	Internal  bool      `json:"internal"`
+	Empty     bool      `json:"empty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GiteaDiscoverer lists repositories from Gitea and Forgejo instances.
+type GiteaDiscoverer struct {
+	client  *http.Client
+	baseURL string
+	cfg     *renovate.Config
+	filter  *filter
+}
+
+func NewGiteaDiscoverer(cfg *renovate.Config) (*GiteaDiscoverer, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required for gitea discovery")
+	}
+
+	// Renovate expects the API URL (https://host/api/v1), but accept the
+	// instance URL as well.
+	baseURL := strings.TrimSuffix(cfg.Endpoint, "/")
+	if !strings.HasSuffix(baseURL, "/api/v1") {
+		baseURL += "/api/v1"
+	}
+
+	flt, err := newFilter(cfg.Discovery.DiscoveryFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GiteaDiscoverer{
+		client:  client,
+		baseURL: baseURL,
+		cfg:     cfg,
+		filter:  flt,
+	}, nil
+}
+
+func (d *GiteaDiscoverer) ListRepositories(ctx context.Context) ([]string, error) {
+	if d.cfg.Discovery.Owner == "" {
+		return d.listRepos(ctx, "/user/repos")
+	}
+
+	owner := url.PathEscape(d.cfg.Discovery.Owner)
+	repos, err := d.listRepos(ctx, "/orgs/"+owner+"/repos")
+	var se *statusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		// Not an organization, try as a user
+		return d.listRepos(ctx, "/users/"+owner+"/repos")
+	}
+	return repos, err
+}
+
+func (d *GiteaDiscoverer) listRepos(ctx context.Context, path string) ([]string, error) {
+	header := http.Header{}
+	if d.cfg.Token != "" {
+		header.Set("Authorization", "token "+d.cfg.Token)
+	}
+
+	var allRepos []string
+	seen := 0
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s%s?page=%d&limit=%d", d.baseURL, path, page, giteaPageSize)
+
+		var repos []giteaRepository
+		resp, err := getJSON(ctx, d.client, u, header, &repos)
+		if err != nil {
+			return nil, err
+		}
+		// The server caps limit at its MAX_RESPONSE_ITEMS, which may be below
+		// giteaPageSize, so a short page is not necessarily the last one.
+		if len(repos) == 0 {
+			break
+		}
+		seen += len(repos)
+
+		for _, repo := range repos {
+			ok, err := d.match(ctx, repo, header)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				allRepos = append(allRepos, repo.FullName)
+			}
+		}
+
+		if total, err := strconv.Atoi(resp.Header.Get("X-Total-Count")); err == nil && seen >= total {
+			break
+		}
+	}
+
+	return allRepos, nil
+}
+
+func (d *GiteaDiscoverer) match(ctx context.Context, repo giteaRepository, header http.Header) (bool, error) {
+	visibility := "public"
+	switch {
+	case repo.Private:
+		visibility = "private"
+	case repo.Internal:
+		visibility = "internal"
+	}
+
+	r := repository{
+		Name:       repo.Name,
+		FullName:   repo.FullName,
+		Topics:     repo.Topics,
+		Archived:   repo.Archived,
+		Fork:       repo.Fork,
+		Visibility: visibility,
+		Empty:      repo.Empty,
+		// Gitea has no push timestamp; updated_at changes on every push
+		PushedAt: repo.UpdatedAt,
+	}
+
+	if !d.filter.match(r) {
+		return false, nil
+	}
+	if len(d.filter.Languages) == 0 {
+		return true, nil
+	}
+
+	// The repository list does not include languages
+	var languages map[string]int64
+	u := d.baseURL + "/repos/" + repo.FullName + "/languages"
+	if _, err := getJSON(ctx, d.client, u, header, &languages); err != nil {
+		return false, fmt.Errorf("failed to get languages of %s: %w", repo.FullName, err)
+	}
+	return d.filter.matchLanguage(primaryLanguage(languages)), nil
+}
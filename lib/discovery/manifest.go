@@ -0,0 +1,150 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	manifestLookupTree   = "tree"
+	manifestLookupSearch = "search"
+
+	// Code search returns at most 1000 results per query
+	maxSearchResults = 1000
+)
+
+// withManifests returns the names of the repositories that contain one of the
+// configured manifests, or of all repositories when none are configured.
+func (d *GitHubDiscoverer) withManifests(ctx context.Context, repos []*github.Repository) ([]string, error) {
+	var allRepos []string
+	if len(d.filter.Manifests) == 0 {
+		for _, repo := range repos {
+			allRepos = append(allRepos, repo.GetFullName())
+		}
+		return allRepos, nil
+	}
+
+	if d.filter.ManifestLookup == manifestLookupSearch {
+		found, complete, err := d.searchManifests(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !complete {
+			log.Printf("code search for manifests of %s returned more than %d results, checking the trees of unmatched repositories", d.cfg.Discovery.Owner, maxSearchResults)
+		}
+		for _, repo := range repos {
+			ok := found[repo.GetFullName()]
+			if !ok && !complete {
+				if ok, err = d.treeHasManifest(ctx, repo); err != nil {
+					return nil, err
+				}
+			}
+			if ok {
+				allRepos = append(allRepos, repo.GetFullName())
+			}
+		}
+		return allRepos, nil
+	}
+
+	for _, repo := range repos {
+		ok, err := d.treeHasManifest(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			allRepos = append(allRepos, repo.GetFullName())
+		}
+	}
+	return allRepos, nil
+}
+
+// treeHasManifest looks for a manifest in the tree of the default branch.
+func (d *GitHubDiscoverer) treeHasManifest(ctx context.Context, repo *github.Repository) (bool, error) {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	tree, resp, err := d.client.Git.GetTree(ctx, owner, name, repo.GetDefaultBranch(), true)
+	if err != nil {
+		// Empty repositories have no tree
+		if resp != nil && (resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get tree of %s: %w", repo.GetFullName(), err)
+	}
+
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" && d.isManifest(entry.GetPath()) {
+			return true, nil
+		}
+	}
+
+	// Very large trees are truncated; keep the repository rather than
+	// dropping it on incomplete information.
+	return tree.GetTruncated(), nil
+}
+
+// isManifest reports whether p is a manifest. Manifests containing a slash
+// must match the full path, others match the file name in any directory.
+func (d *GitHubDiscoverer) isManifest(p string) bool {
+	for _, m := range d.filter.Manifests {
+		if strings.Contains(m, "/") {
+			if p == strings.TrimPrefix(m, "/") {
+				return true
+			}
+		} else if path.Base(p) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// searchManifests returns the repositories of the owner containing a
+// manifest according to code search. complete is false when a query had
+// more results than code search returns, so repositories may be missing.
+func (d *GitHubDiscoverer) searchManifests(ctx context.Context) (found map[string]bool, complete bool, err error) {
+	found = make(map[string]bool)
+	complete = true
+
+	for _, m := range d.filter.Manifests {
+		query := "user:" + d.cfg.Discovery.Owner
+		dir, file := path.Split(strings.TrimPrefix(m, "/"))
+		query += " filename:" + file
+		if dir != "" {
+			query += " path:" + strings.TrimSuffix(dir, "/")
+		}
+
+		opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			result, resp, err := d.client.Search.Code(ctx, query, opt)
+			if err != nil {
+				var rle *github.RateLimitError
+				if errors.As(err, &rle) {
+					return nil, false, fmt.Errorf("code search rate limit exceeded, retry after %s: %w", rle.Rate.Reset.Time, err)
+				}
+				return nil, false, fmt.Errorf("failed to search for %s: %w", m, err)
+			}
+
+			for _, code := range result.CodeResults {
+				if dir != "" && code.GetPath() != strings.TrimPrefix(m, "/") {
+					continue
+				}
+				found[code.GetRepository().GetFullName()] = true
+			}
+
+			if result.GetIncompleteResults() || result.GetTotal() > maxSearchResults {
+				complete = false
+			}
+			if resp.NextPage == 0 || opt.Page*opt.PerPage >= maxSearchResults {
+				break
+			}
+			opt.Page = resp.NextPage
+		}
+	}
+
+	return found, complete, nil
+}
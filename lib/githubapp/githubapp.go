@@ -0,0 +1,181 @@
+package githubapp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	defaultEndpoint = "https://api.github.com"
+
+	// Installation tokens are valid for an hour. API clients reuse a token
+	// until shortly before it expires.
+	refreshBefore = 5 * time.Minute
+
+	requestTimeout = 30 * time.Second
+)
+
+// installationTokenSource mints GitHub App installation tokens.
+type installationTokenSource struct {
+	client         *http.Client
+	endpoint       string
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+}
+
+// TokenSource mints installation tokens for a GitHub App installation.
+// Token reuses a token until shortly before it expires, which suits API
+// clients; NewToken always mints one, for processes that hold on to the
+// token for as long as they run.
+type TokenSource struct {
+	reuse oauth2.TokenSource
+	mint  *installationTokenSource
+}
+
+func (s *TokenSource) Token() (*oauth2.Token, error) {
+	return s.reuse.Token()
+}
+
+// NewToken mints an installation token valid for the full hour.
+func (s *TokenSource) NewToken() (*oauth2.Token, error) {
+	return s.mint.Token()
+}
+
+// NewTokenSource returns a token source for the given GitHub App
+// installation. endpoint is the REST API URL; empty means github.com.
+func NewTokenSource(endpoint string, appID, installationID int64, privateKey []byte) (*TokenSource, error) {
+	if appID == 0 || installationID == 0 {
+		return nil, fmt.Errorf("app ID and installation ID are required")
+	}
+
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	src := &installationTokenSource{
+		client:         http.DefaultClient,
+		endpoint:       strings.TrimSuffix(endpoint, "/"),
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+	}
+	return &TokenSource{
+		reuse: oauth2.ReuseTokenSourceWithExpiry(nil, src, refreshBefore),
+		mint:  src,
+	}, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	jwt, err := s.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", s.endpoint, s.installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request installation token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("installation token request failed with status code %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode installation token: %w", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: result.Token,
+		TokenType:   "token",
+		Expiry:      result.ExpiresAt,
+	}, nil
+}
+
+// jwt returns the RS256 token authenticating as the app itself.
+func (s *installationTokenSource) jwt(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+
+	claims, err := json.Marshal(map[string]int64{
+		// Backdated to allow for clock drift, and at most 10 minutes long
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": s.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(header)
+	b.WriteByte('.')
+	b.WriteString(base64.RawURLEncoding.EncodeToString(claims))
+
+	digest := sha256.Sum256(b.Bytes())
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	b.WriteByte('.')
+	b.WriteString(base64.RawURLEncoding.EncodeToString(sig))
+	return b.String(), nil
+}
@@ -0,0 +1,300 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+const (
+	discordResolvedColor = 0x7F8C8D
+	discordSecurityColor = 0x8E0000
+	discordProblemColor  = 0xE67E22
+
+	discordMaxEmbeds     = 10
+	discordMaxEmbedChars = 6000
+	discordMaxTitleChars = 256
+
+	discordMaxDescriptionChars = 4096
+	discordMaxFieldNameChars   = 256
+	discordMaxFieldValueChars  = 1024
+
+	// Webhooks are rate limited to a few messages per second; rate limited
+	// messages are retried after the delay Discord asks for.
+	discordMaxAttempts   = 5
+	discordMaxRetryAfter = time.Minute
+)
+
+type DiscordNotifier struct {
+	URL string
+}
+
+func NewDiscordNotifier(url string) *DiscordNotifier {
+	return &DiscordNotifier{URL: url}
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+}
+
+// size returns the number of characters Discord counts towards the 6000 limit.
+func (e discordEmbed) size() int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	for _, f := range e.Fields {
+		n += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return n
+}
+
+type discordPayload struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+func (n *DiscordNotifier) Notify(ctx context.Context, report Report) error {
+	if n.URL == "" {
+		return nil
+	}
+
+	if report.Empty() {
+		return nil
+	}
+
+	return n.sendEmbeds(ctx, fmt.Sprintf("📢 **Dependency Updates for %s**", report.Repo), discordReportEmbeds(report, ""))
+}
+
+func (n *DiscordNotifier) NotifyDigest(ctx context.Context, reports []Report) error {
+	if n.URL == "" {
+		return nil
+	}
+
+	summary := Summarize(reports)
+	if summary.Repos == 0 {
+		return nil
+	}
+
+	var embeds []discordEmbed
+	for _, r := range pendingReports(reports) {
+		embeds = append(embeds, discordReportEmbeds(r, r.Repo)...)
+	}
+
+	content := fmt.Sprintf("📢 **Dependency Updates Digest**\n%d update(s) in %d repositories (%s)", summary.Total, summary.Repos, summary.Totals())
+	return n.sendEmbeds(ctx, content, embeds)
+}
+
+func (n *DiscordNotifier) sendEmbeds(ctx context.Context, content string, embeds []discordEmbed) error {
+	// A single embed over Discord's limits fails the whole message
+	for i := range embeds {
+		e := &embeds[i]
+		e.Title = discordTruncate(e.Title, discordMaxTitleChars)
+		e.Description = discordTruncate(e.Description, discordMaxDescriptionChars)
+		for j := range e.Fields {
+			e.Fields[j].Name = discordTruncate(e.Fields[j].Name, discordMaxFieldNameChars)
+			e.Fields[j].Value = discordTruncate(e.Fields[j].Value, discordMaxFieldValueChars)
+		}
+	}
+
+	for _, batch := range discordBatches(embeds) {
+		payload := discordPayload{Content: content, Embeds: batch}
+		if err := n.send(ctx, payload); err != nil {
+			return err
+		}
+		// Only the first message carries the header
+		content = ""
+	}
+
+	return nil
+}
+
+// discordReportEmbeds builds one embed per update. When repo is set it is
+// added as a field so digest embeds can be told apart.
+func discordReportEmbeds(report Report, repo string) []discordEmbed {
+	var embeds []discordEmbed
+	for _, u := range report.Updates {
+		embeds = append(embeds, discordUpdateEmbed(u, repo))
+	}
+	for _, u := range report.Resolved {
+		embed := discordUpdateEmbed(u, repo)
+		embed.Title = "✔️ Resolved: " + embed.Title
+		embed.Color = discordResolvedColor
+		embeds = append(embeds, embed)
+	}
+	if len(report.Problems) > 0 {
+		embed := discordEmbed{
+			Title:       "⚠️ " + problemsTitle(report.Problems),
+			Description: strings.Join(problemLines(report.Problems), "\n"),
+			Color:       discordProblemColor,
+		}
+		if repo != "" {
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: "Repository", Value: repo, Inline: true})
+		}
+		embeds = append(embeds, embed)
+	}
+	return embeds
+}
+
+func discordUpdateEmbed(u renovate.UpdateInfo, repo string) discordEmbed {
+	embed := discordEmbed{
+		Title:       u.DepName,
+		URL:         discordURL(u.Link()),
+		Description: fmt.Sprintf("`%s` → `%s`", u.CurrentVersion, u.NewVersion),
+		Color:       discordUpdateTypeColor(u.UpdateType),
+	}
+	// Discord renders the timestamp as the release date in the embed footer
+	if released, ok := u.ReleaseTime(); ok {
+		embed.Timestamp = released.Format(time.RFC3339)
+	}
+	if repo != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Repository", Value: repo, Inline: true})
+	}
+	if u.PackageFile != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "File", Value: u.PackageFile, Inline: true})
+	}
+	if u.UpdateType != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Type", Value: u.UpdateType, Inline: true})
+	}
+	if age := releaseAge(u); age != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Released", Value: age, Inline: true})
+	}
+	if note := securityNote(u); note != "" {
+		embed.Title = "🛡️ " + embed.Title
+		embed.Color = discordSecurityColor
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Security Fix", Value: note})
+	}
+
+	return embed
+}
+
+// discordTruncate cuts s to at most n characters, marking the cut with "…".
+func discordTruncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// discordURL returns link if Discord accepts it as an embed URL, which must
+// be an absolute http(s) URL, and "" otherwise.
+func discordURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return link
+}
+
+func discordUpdateTypeColor(updateType string) int {
+	switch updateType {
+	case "major":
+		return 0xE74C3C
+	case "minor":
+		return 0xF1C40F
+	case "patch":
+		return 0x2ECC71
+	default:
+		return 0x95A5A6
+	}
+}
+
+// discordBatches splits embeds so that each message stays within Discord's
+// per-message embed count and total character limits.
+func discordBatches(embeds []discordEmbed) [][]discordEmbed {
+	var batches [][]discordEmbed
+	var current []discordEmbed
+	size := 0
+
+	for _, e := range embeds {
+		s := e.size()
+		if len(current) > 0 && (len(current) >= discordMaxEmbeds || size+s > discordMaxEmbedChars) {
+			batches = append(batches, current)
+			current = nil
+			size = 0
+		}
+		current = append(current, e)
+		size += s
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+func (n *DiscordNotifier) send(ctx context.Context, payload discordPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal discord payload: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create discord request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send discord notification: %w", err)
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < discordMaxAttempts {
+			timer := time.NewTimer(discordRetryAfter(resp.Header, body))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("failed to send discord notification: %w", ctx.Err())
+			case <-timer.C:
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("discord webhook failed with status code: %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// discordRetryAfter returns the delay requested by a rate limited response,
+// from the Retry-After header or the retry_after field of the body.
+func discordRetryAfter(header http.Header, body []byte) time.Duration {
+	seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64)
+	if err != nil {
+		var rateLimit struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if json.Unmarshal(body, &rateLimit) == nil {
+			seconds = rateLimit.RetryAfter
+		}
+	}
+
+	delay := time.Duration(seconds * float64(time.Second))
+	if delay <= 0 {
+		delay = time.Second
+	}
+	return min(delay, discordMaxRetryAfter)
+}
@@ -0,0 +1,294 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+const (
+	EmailTLSStartTLS = "starttls"
+	EmailTLSImplicit = "tls"
+	EmailTLSNone     = "none"
+)
+
+type EmailNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+	TLS      string
+}
+
+func NewEmailNotifier(host string, port int, username, password, from string, to []string, tlsMode string) (*EmailNotifier, error) {
+	tlsMode = strings.ToLower(strings.TrimSpace(tlsMode))
+	switch tlsMode {
+	case "":
+		tlsMode = EmailTLSStartTLS
+	case EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone:
+	default:
+		// Falling back to plaintext would send reports unencrypted without notice
+		return nil, fmt.Errorf("invalid email tls mode %q: expected %q, %q or %q", tlsMode, EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone)
+	}
+	if port == 0 {
+		switch tlsMode {
+		case EmailTLSImplicit:
+			port = 465
+		default:
+			port = 587
+		}
+	}
+
+	return &EmailNotifier{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+		To:       to,
+		TLS:      tlsMode,
+	}, nil
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, report Report) error {
+	if n.Host == "" || n.From == "" || len(n.To) == 0 {
+		return nil
+	}
+
+	if report.Empty() {
+		return nil
+	}
+
+	subject := fmt.Sprintf("Dependency Updates for %s", report.Repo)
+	return n.send(ctx, subject, "", []Report{report})
+}
+
+// NotifyDigest sends a single email covering every repository that has updates.
+func (n *EmailNotifier) NotifyDigest(ctx context.Context, reports []Report) error {
+	if n.Host == "" || n.From == "" || len(n.To) == 0 {
+		return nil
+	}
+
+	summary := Summarize(reports)
+	if summary.Repos == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("Dependency Updates Digest: %d update(s) in %d repositories", summary.Total, summary.Repos)
+	totals := fmt.Sprintf("%d update(s) in %d repositories (%s)", summary.Total, summary.Repos, summary.Totals())
+	return n.send(ctx, subject, totals, pendingReports(reports))
+}
+
+func emailTextBody(summary string, reports []Report) string {
+	var sb strings.Builder
+	if summary != "" {
+		sb.WriteString(summary + "\n\n")
+	}
+	for i, r := range reports {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("Dependency Updates for %s:\n", r.Repo))
+		writeEmailUpdates(&sb, r.Updates)
+		if len(r.Resolved) > 0 {
+			sb.WriteString("Resolved:\n")
+			writeEmailUpdates(&sb, r.Resolved)
+		}
+		if len(r.Problems) > 0 {
+			sb.WriteString(problemsTitle(r.Problems) + ":\n")
+			for _, line := range problemLines(r.Problems) {
+				sb.WriteString("- " + line + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func writeEmailUpdates(sb *strings.Builder, updates []renovate.UpdateInfo) {
+	for _, u := range updates {
+		sb.WriteString(fmt.Sprintf("- %s: %s -> %s", u.DepName, u.CurrentVersion, u.NewVersion))
+		if u.PackageFile != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", u.PackageFile))
+		}
+		if u.UpdateType != "" {
+			sb.WriteString(fmt.Sprintf(" [%s]", u.UpdateType))
+		}
+		if note := securityNote(u); note != "" {
+			sb.WriteString(fmt.Sprintf(" [security: %s]", note))
+		}
+		if age := releaseAge(u); age != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", age))
+		}
+		if link := u.Link(); link != "" {
+			sb.WriteString(" " + link)
+		}
+		sb.WriteString("\n")
+	}
+}
+
+var emailHTMLTemplate = template.Must(template.New("email").Funcs(template.FuncMap{"securityNote": securityNote, "releaseAge": releaseAge, "problemsTitle": problemsTitle, "problemLines": problemLines}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; font-size: 14px;">
+<h2>📢 Dependency Updates</h2>
+{{if .Summary}}<p>{{.Summary}}</p>{{end}}
+{{range .Reports}}
+<h3>{{.Repo}}</h3>
+{{if .Updates}}{{template "updates" .Updates}}{{end}}
+{{if .Resolved}}<h4>✔️ Resolved</h4>
+{{template "updates" .Resolved}}{{end}}
+{{if .Problems}}<h4 style="color: #b35900;">⚠️ {{problemsTitle .Problems}}</h4>
+<ul>{{range problemLines .Problems}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{end}}
+</body>
+</html>
+{{define "updates"}}<table cellpadding="6" cellspacing="0" border="1" style="border-collapse: collapse;">
+<tr style="background: #f0f0f0;"><th align="left">Package</th><th align="left">Version</th><th align="left">File</th><th align="left">Type</th><th align="left">Released</th></tr>
+{{range .}}<tr{{if .IsVulnerabilityAlert}} style="background: #fdecea;"{{end}}><td>{{with .Link}}<a href="{{.}}">{{end}}{{.DepName}}{{if .Link}}</a>{{end}}</td><td>{{.CurrentVersion}} → {{.NewVersion}}</td><td>{{.PackageFile}}</td><td>{{.UpdateType}}{{with securityNote .}}<br><strong>🛡️ {{.}}</strong>{{end}}</td><td>{{releaseAge .}}</td></tr>
+{{end}}</table>
+{{end}}`))
+
+func (n *EmailNotifier) buildMessage(subject, summary string, reports []Report) ([]byte, error) {
+	data := struct {
+		Summary string
+		Reports []Report
+	}{Summary: summary, Reports: reports}
+
+	var htmlBody bytes.Buffer
+	if err := emailHTMLTemplate.Execute(&htmlBody, data); err != nil {
+		return nil, fmt.Errorf("failed to render email html: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", emailTextBody(summary, reports)},
+		{"text/html; charset=utf-8", htmlBody.String()},
+	}
+	for _, p := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(p.content)); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish email body: %w", err)
+	}
+
+	var msg bytes.Buffer
+	msg.WriteString(fmt.Sprintf("From: %s\r\n", n.From))
+	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(n.To, ", ")))
+	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject)))
+	msg.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s\r\n", mw.Boundary()))
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func (n *EmailNotifier) send(ctx context.Context, subject, summary string, reports []Report) error {
+	msg, err := n.buildMessage(subject, summary, reports)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+	tlsConfig := &tls.Config{ServerName: n.Host}
+
+	var conn net.Conn
+	dialer := &net.Dialer{}
+	switch n.TLS {
+	case EmailTLSImplicit:
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	case EmailTLSStartTLS, EmailTLSNone:
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return fmt.Errorf("invalid email tls mode %q", n.TLS)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create smtp client: %w", err)
+	}
+	defer client.Close()
+
+	if n.TLS == EmailTLSStartTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	if n.Username != "" {
+		auth := smtp.PlainAuth("", n.Username, n.Password, n.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate with smtp server: %w", err)
+		}
+	}
+
+	from, err := mail.ParseAddress(n.From)
+	if err != nil {
+		return fmt.Errorf("invalid email sender %q: %w", n.From, err)
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("failed to set email sender: %w", err)
+	}
+	for _, to := range n.To {
+		rcpt, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("invalid email recipient %q: %w", to, err)
+		}
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("failed to set email recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start email data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write email data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return client.Quit()
+}
@@ -2,10 +2,202 @@ package notifier
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/snowmerak/renovates/lib/renovate"
 )
 
 type Notifier interface {
-	Notify(ctx context.Context, repo string, updates []renovate.UpdateInfo) error
+	Notify(ctx context.Context, report Report) error
+}
+
+// DigestNotifier sends one aggregated notification for a whole run instead
+// of one per repository.
+type DigestNotifier interface {
+	NotifyDigest(ctx context.Context, reports []Report) error
+}
+
+// Report is the result of a single repository run.
+type Report struct {
+	Repo    string                `json:"repo"`
+	Updates []renovate.UpdateInfo `json:"updates"`
+	// Resolved holds updates reported by the previous run that are no longer pending.
+	Resolved []renovate.UpdateInfo `json:"resolved,omitempty"`
+	// Problems holds the warnings and errors that kept Renovate from fully
+	// scanning the repository.
+	Problems []renovate.Problem `json:"problems,omitempty"`
+	// URL is the web page of the repository, if known.
+	URL string `json:"url,omitempty"`
+	// LogPath is the saved raw Renovate log, when logs are kept.
+	LogPath string `json:"logPath,omitempty"`
+}
+
+// Empty reports whether there is nothing to announce for the repository.
+func (r Report) Empty() bool {
+	return len(r.Updates) == 0 && len(r.Resolved) == 0 && len(r.Problems) == 0
+}
+
+func New(cfg renovate.NotifierConfig) (Notifier, error) {
+	switch cfg.Type {
+	case "stdout":
+		return NewStdoutNotifier(), nil
+	case "webhook":
+		return NewWebhookNotifier(cfg.URL), nil
+	case "teams":
+		return NewTeamsNotifier(cfg.URL), nil
+	case "telegram":
+		return NewTelegramNotifier(cfg.Token, cfg.ChatID), nil
+	case "slack":
+		return NewSlackNotifier(cfg.URL, cfg.Token, cfg.Channel), nil
+	case "discord":
+		return NewDiscordNotifier(cfg.URL), nil
+	case "email":
+		return NewEmailNotifier(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.From, cfg.To, cfg.TLS)
+	default:
+		return nil, fmt.Errorf("unsupported notifier type: %s", cfg.Type)
+	}
+}
+
+// maxProblems caps how many problems are listed per repository.
+const maxProblems = 10
+
+var updateTypeOrder = []string{"major", "minor", "patch"}
+
+// DigestSummary holds the totals shown at the top of a digest.
+type DigestSummary struct {
+	Total      int
+	Security   int
+	Resolved   int
+	Repos      int
+	Incomplete int
+	ByType     map[string]int
+}
+
+func Summarize(reports []Report) DigestSummary {
+	s := DigestSummary{ByType: make(map[string]int)}
+	for _, r := range reports {
+		if r.Empty() {
+			continue
+		}
+		s.Repos++
+		s.Resolved += len(r.Resolved)
+		if len(r.Problems) > 0 {
+			s.Incomplete++
+		}
+		for _, u := range r.Updates {
+			s.Total++
+			if u.IsVulnerabilityAlert {
+				s.Security++
+			}
+			updateType := u.UpdateType
+			if updateType == "" {
+				updateType = "other"
+			}
+			s.ByType[updateType]++
+		}
+	}
+	return s
+}
+
+// Totals formats the per update type counts, e.g. "major: 1, minor: 3, patch: 7".
+func (s DigestSummary) Totals() string {
+	var parts []string
+	if s.Security > 0 {
+		parts = append(parts, fmt.Sprintf("security: %d", s.Security))
+	}
+	seen := make(map[string]bool)
+	for _, t := range updateTypeOrder {
+		seen[t] = true
+		if c := s.ByType[t]; c > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", t, c))
+		}
+	}
+	var rest []string
+	for t := range s.ByType {
+		if !seen[t] {
+			rest = append(rest, t)
+		}
+	}
+	sort.Strings(rest)
+	for _, t := range rest {
+		parts = append(parts, fmt.Sprintf("%s: %d", t, s.ByType[t]))
+	}
+	if s.Resolved > 0 {
+		parts = append(parts, fmt.Sprintf("resolved: %d", s.Resolved))
+	}
+	if s.Incomplete > 0 {
+		parts = append(parts, fmt.Sprintf("incomplete scans: %d", s.Incomplete))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// pendingReports drops reports that have nothing to announce.
+func pendingReports(reports []Report) []Report {
+	var pending []Report
+	for _, r := range reports {
+		if !r.Empty() {
+			pending = append(pending, r)
+		}
+	}
+	return pending
+}
+
+// securityNote describes the vulnerabilities fixed by an update, e.g.
+// "HIGH: CVE-2024-1234, GHSA-xxxx-xxxx-xxxx", or "" for routine updates.
+func securityNote(u renovate.UpdateInfo) string {
+	if !u.IsVulnerabilityAlert {
+		return ""
+	}
+
+	note := u.VulnerabilitySeverity
+	if len(u.Vulnerabilities) > 0 {
+		if note != "" {
+			note += ": "
+		}
+		note += strings.Join(u.Vulnerabilities, ", ")
+	}
+	if note == "" {
+		note = "vulnerability fix"
+	}
+	return note
+}
+
+// releaseAge describes how long ago the new version was released, e.g.
+// "released 3 days ago", or "" when the release time is unknown.
+func releaseAge(u renovate.UpdateInfo) string {
+	released, ok := u.ReleaseTime()
+	if !ok {
+		return ""
+	}
+
+	days := int(time.Since(released).Hours() / 24)
+	switch {
+	case days <= 0:
+		return "released today"
+	case days == 1:
+		return "released 1 day ago"
+	default:
+		return fmt.Sprintf("released %d days ago", days)
+	}
+}
+
+// problemsTitle is the heading shown above the problems of a repository.
+func problemsTitle(problems []renovate.Problem) string {
+	return fmt.Sprintf("Could not be fully scanned (%d problem(s))", len(problems))
+}
+
+// problemLines formats problems one per line, capped at maxProblems.
+func problemLines(problems []renovate.Problem) []string {
+	var lines []string
+	for i, p := range problems {
+		if i == maxProblems {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(problems)-maxProblems))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("[%s] %s", strings.ToUpper(p.Level), p.String()))
+	}
+	return lines
 }
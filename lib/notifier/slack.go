@@ -0,0 +1,289 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+const (
+	slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+	// Slack rejects messages with more than 50 blocks.
+	slackMaxBodyBlocks = 45
+	// Slack rejects context elements and section texts above these lengths.
+	slackMaxContextText = 2000
+	slackMaxSectionText = 3000
+)
+
+type SlackNotifier struct {
+	URL     string
+	Token   string
+	Channel string
+}
+
+func NewSlackNotifier(url, token, channel string) *SlackNotifier {
+	return &SlackNotifier{
+		URL:     url,
+		Token:   token,
+		Channel: channel,
+	}
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, report Report) error {
+	if n.URL == "" && (n.Token == "" || n.Channel == "") {
+		return nil
+	}
+
+	if report.Empty() {
+		return nil
+	}
+
+	title := "📢 Dependency Updates"
+	subtitle := fmt.Sprintf("*%s* · %d update(s) detected", report.Repo, len(report.Updates))
+	if len(report.Resolved) > 0 {
+		subtitle += fmt.Sprintf(", %d resolved", len(report.Resolved))
+	}
+	if len(report.Problems) > 0 {
+		subtitle += fmt.Sprintf(", %d problem(s)", len(report.Problems))
+	}
+	return n.sendBlocks(ctx, fmt.Sprintf("📢 Dependency Updates for %s", report.Repo), title, subtitle, slackReportBlocks(report))
+}
+
+func (n *SlackNotifier) NotifyDigest(ctx context.Context, reports []Report) error {
+	if n.URL == "" && (n.Token == "" || n.Channel == "") {
+		return nil
+	}
+
+	summary := Summarize(reports)
+	if summary.Repos == 0 {
+		return nil
+	}
+
+	var blocks []interface{}
+	for _, r := range pendingReports(reports) {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": fmt.Sprintf("📁 *%s*", r.Repo)},
+		})
+		blocks = append(blocks, slackReportBlocks(r)...)
+		blocks = append(blocks, map[string]interface{}{"type": "divider"})
+	}
+
+	title := "📢 Dependency Updates Digest"
+	subtitle := fmt.Sprintf("%d update(s) in %d repositories · %s", summary.Total, summary.Repos, summary.Totals())
+	return n.sendBlocks(ctx, title, title, subtitle, blocks)
+}
+
+// sendBlocks posts the blocks below a header, splitting them over several
+// messages when they exceed Slack's block limit.
+func (n *SlackNotifier) sendBlocks(ctx context.Context, text, title, subtitle string, blocks []interface{}) error {
+	for start := 0; start < len(blocks); start += slackMaxBodyBlocks {
+		end := min(start+slackMaxBodyBlocks, len(blocks))
+		message := slackHeaderBlocks(title, subtitle, start > 0)
+		message = append(message, blocks[start:end]...)
+
+		payload := map[string]interface{}{
+			"text":   text,
+			"blocks": message,
+		}
+		if err := n.send(ctx, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func slackHeaderBlocks(title, subtitle string, continued bool) []interface{} {
+	if continued {
+		title += " (continued)"
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": title, "emoji": true},
+		},
+		map[string]interface{}{
+			"type": "context",
+			"elements": []interface{}{
+				map[string]interface{}{"type": "mrkdwn", "text": subtitle},
+			},
+		},
+		map[string]interface{}{"type": "divider"},
+	}
+}
+
+func slackReportBlocks(report Report) []interface{} {
+	var blocks []interface{}
+	for _, u := range report.Updates {
+		blocks = append(blocks, slackUpdateBlock(u))
+	}
+	if len(report.Resolved) > 0 {
+		var lines []string
+		for _, u := range report.Resolved {
+			lines = append(lines, fmt.Sprintf("~%s~ `%s` → `%s`", u.DepName, u.CurrentVersion, u.NewVersion))
+		}
+		for _, text := range slackChunks("✔️ *Resolved:* ", ", ", lines, slackMaxContextText) {
+			blocks = append(blocks, map[string]interface{}{
+				"type": "context",
+				"elements": []interface{}{
+					map[string]interface{}{"type": "mrkdwn", "text": text},
+				},
+			})
+		}
+	}
+	if len(report.Problems) > 0 {
+		var lines []string
+		for _, line := range problemLines(report.Problems) {
+			lines = append(lines, "• "+slackEscape(line))
+		}
+		prefix := fmt.Sprintf("⚠️ *%s*\n", problemsTitle(report.Problems))
+		for _, text := range slackChunks(prefix, "\n", lines, slackMaxSectionText) {
+			blocks = append(blocks, map[string]interface{}{
+				"type": "section",
+				"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+			})
+		}
+	}
+	return blocks
+}
+
+// slackChunks joins lines with sep into texts of at most limit bytes, the
+// first one starting with prefix. Lines that do not fit on their own are
+// truncated.
+func slackChunks(prefix, sep string, lines []string, limit int) []string {
+	var chunks []string
+	text := prefix
+	empty := true
+	for _, line := range lines {
+		if !empty && len(text)+len(sep)+len(line) > limit {
+			chunks = append(chunks, text)
+			text, empty = "", true
+		}
+		if !empty {
+			text += sep
+		}
+		if room := limit - len(text); len(line) > room {
+			line = truncateUTF8(line, room-len("…")) + "…"
+		}
+		text += line
+		empty = false
+	}
+	return append(chunks, text)
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+func slackUpdateBlock(u renovate.UpdateInfo) map[string]interface{} {
+	name := u.DepName
+	if link := u.Link(); link != "" {
+		name = fmt.Sprintf("<%s|%s>", link, u.DepName)
+	}
+	text := fmt.Sprintf("📦 *%s*\n`%s` → `%s`", name, u.CurrentVersion, u.NewVersion)
+	if u.PackageFile != "" {
+		text += fmt.Sprintf(" in `%s`", u.PackageFile)
+	}
+	if age := releaseAge(u); age != "" {
+		text += fmt.Sprintf(" _(%s)_", age)
+	}
+
+	block := map[string]interface{}{
+		"type": "section",
+		"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+	}
+
+	var fields []interface{}
+	if u.UpdateType != "" {
+		fields = append(fields, map[string]interface{}{"type": "mrkdwn", "text": slackUpdateTypeText(u.UpdateType)})
+	}
+	if note := securityNote(u); note != "" {
+		fields = append(fields, map[string]interface{}{"type": "mrkdwn", "text": "🛡️ *Security fix* " + note})
+	}
+	if len(fields) > 0 {
+		block["fields"] = fields
+	}
+
+	return block
+}
+
+func slackUpdateTypeText(updateType string) string {
+	switch updateType {
+	case "major":
+		return "🚨 " + updateType
+	case "minor":
+		return "⚠️ " + updateType
+	case "patch":
+		return "✅ " + updateType
+	default:
+		return updateType
+	}
+}
+
+func (n *SlackNotifier) send(ctx context.Context, payload map[string]interface{}) error {
+	url := n.URL
+	if n.Token != "" && n.Channel != "" {
+		// Bot token mode uses the Web API instead of an incoming webhook
+		url = slackPostMessageURL
+		payload["channel"] = n.Channel
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if url == slackPostMessageURL {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send slack notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("slack api failed with status code: %d", resp.StatusCode)
+	}
+
+	if url == slackPostMessageURL {
+		// chat.postMessage reports failures in the body with a 200 status
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("failed to decode slack response: %w", err)
+		}
+		if !result.OK {
+			return fmt.Errorf("slack api returned error: %s", result.Error)
+		}
+	}
+
+	return nil
+}
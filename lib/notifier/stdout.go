@@ -13,13 +13,50 @@ func NewStdoutNotifier() *StdoutNotifier {
 	return &StdoutNotifier{}
 }
 
-func (n *StdoutNotifier) Notify(ctx context.Context, repo string, updates []renovate.UpdateInfo) error {
-	if len(updates) == 0 {
-		fmt.Printf("Notification for %s:\nNo updates needed.\n", repo)
+func (n *StdoutNotifier) Notify(ctx context.Context, report Report) error {
+	if report.Empty() {
+		fmt.Printf("Notification for %s:\nNo updates needed.\n", report.Repo)
 		return nil
 	}
 
-	fmt.Printf("Notification for %s:\nDependency Updates:\n", repo)
+	fmt.Printf("Notification for %s:\n", report.Repo)
+	printReport(report)
+	return nil
+}
+
+func (n *StdoutNotifier) NotifyDigest(ctx context.Context, reports []Report) error {
+	summary := Summarize(reports)
+	if summary.Repos == 0 {
+		fmt.Printf("Dependency Digest for %d repositories:\nNo updates needed.\n", len(reports))
+		return nil
+	}
+
+	fmt.Printf("Dependency Digest: %d update(s) in %d repositories (%s)\n", summary.Total, summary.Repos, summary.Totals())
+	for _, r := range pendingReports(reports) {
+		fmt.Printf("\n%s:\n", r.Repo)
+		printReport(r)
+	}
+	return nil
+}
+
+func printReport(report Report) {
+	if len(report.Updates) > 0 {
+		fmt.Println("Dependency Updates:")
+		printUpdates(report.Updates)
+	}
+	if len(report.Resolved) > 0 {
+		fmt.Println("Resolved Updates:")
+		printUpdates(report.Resolved)
+	}
+	if len(report.Problems) > 0 {
+		fmt.Printf("%s:\n", problemsTitle(report.Problems))
+		for _, line := range problemLines(report.Problems) {
+			fmt.Printf("- %s\n", line)
+		}
+	}
+}
+
+func printUpdates(updates []renovate.UpdateInfo) {
 	for _, u := range updates {
 		msg := fmt.Sprintf("- %s: %s -> %s", u.DepName, u.CurrentVersion, u.NewVersion)
 		if u.PackageFile != "" {
@@ -28,7 +65,15 @@ func (n *StdoutNotifier) Notify(ctx context.Context, repo string, updates []reno
 		if u.UpdateType != "" {
 			msg += fmt.Sprintf(" [%s]", u.UpdateType)
 		}
+		if note := securityNote(u); note != "" {
+			msg += fmt.Sprintf(" [security: %s]", note)
+		}
+		if age := releaseAge(u); age != "" {
+			msg += fmt.Sprintf(" (%s)", age)
+		}
+		if link := u.Link(); link != "" {
+			msg += " " + link
+		}
 		fmt.Println(msg)
 	}
-	return nil
 }
@@ -10,6 +10,10 @@ import (
 	"github.com/snowmerak/renovates/lib/renovate"
 )
 
+// Teams and Power Automate webhooks reject payloads above about 28 KB; cards
+// are split to stay below this, leaving room for the envelope.
+const teamsMaxCardSize = 24 << 10
+
 type TeamsNotifier struct {
 	URL string
 }
@@ -18,15 +22,131 @@ func NewTeamsNotifier(url string) *TeamsNotifier {
 	return &TeamsNotifier{URL: url}
 }
 
-func (n *TeamsNotifier) Notify(ctx context.Context, repo string, updates []renovate.UpdateInfo) error {
+func (n *TeamsNotifier) Notify(ctx context.Context, report Report) error {
+	if n.URL == "" {
+		return nil
+	}
+
+	if report.Empty() {
+		return nil
+	}
+
+	subtitle := fmt.Sprintf("새로운 의존성 업데이트가 감지되었습니다. (%s)", report.Repo)
+	return n.sendCards(ctx, "📢 Dependency Updates", subtitle, teamsReportBlocks(report), report.URL)
+}
+
+func (n *TeamsNotifier) NotifyDigest(ctx context.Context, reports []Report) error {
 	if n.URL == "" {
 		return nil
 	}
 
-	if len(updates) == 0 {
+	summary := Summarize(reports)
+	if summary.Repos == 0 {
 		return nil
 	}
 
+	var blocks []interface{}
+	for _, r := range pendingReports(reports) {
+		blocks = append(blocks, map[string]interface{}{
+			"type":      "TextBlock",
+			"text":      fmt.Sprintf("📁 %s", r.Repo),
+			"weight":    "Bolder",
+			"size":      "Medium",
+			"spacing":   "Large",
+			"separator": true,
+			"wrap":      true,
+		})
+		blocks = append(blocks, teamsReportBlocks(r)...)
+	}
+
+	subtitle := fmt.Sprintf("%d개 저장소에서 %d개의 의존성 업데이트가 감지되었습니다. (%s)", summary.Repos, summary.Total, summary.Totals())
+	return n.sendCards(ctx, "📢 Dependency Updates Digest", subtitle, blocks, "")
+}
+
+// sendCards posts the blocks below a header, splitting them over several
+// cards when they exceed the webhook's payload limit.
+func (n *TeamsNotifier) sendCards(ctx context.Context, title, subtitle string, blocks []interface{}, repoURL string) error {
+	var card []interface{}
+	size := 0
+	continued := false
+	for _, b := range blocks {
+		data, err := json.Marshal(b)
+		if err != nil {
+			return fmt.Errorf("failed to marshal teams payload: %w", err)
+		}
+
+		if len(card) > 0 && size+len(data) > teamsMaxCardSize {
+			if err := n.send(ctx, append(teamsHeaderBlocks(title, subtitle, continued), card...), repoURL); err != nil {
+				return err
+			}
+			card, size, continued = nil, 0, true
+		}
+		card = append(card, b)
+		size += len(data)
+	}
+
+	return n.send(ctx, append(teamsHeaderBlocks(title, subtitle, continued), card...), repoURL)
+}
+
+func teamsHeaderBlocks(title, subtitle string, continued bool) []interface{} {
+	if continued {
+		title += " (continued)"
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"type":   "TextBlock",
+			"text":   title,
+			"weight": "Bolder",
+			"size":   "Large",
+			"color":  "Accent",
+		},
+		map[string]interface{}{
+			"type":     "TextBlock",
+			"text":     subtitle,
+			"isSubtle": true,
+			"wrap":     true,
+		},
+	}
+}
+
+func teamsReportBlocks(report Report) []interface{} {
+	var blocks []interface{}
+	if len(report.Updates) > 0 {
+		// Rows are separate blocks so that long reports can be split
+		blocks = append(blocks, teamsColumnHeader())
+		blocks = append(blocks, teamsUpdateRows(report.Updates)...)
+	}
+	if len(report.Resolved) > 0 {
+		blocks = append(blocks,
+			map[string]interface{}{
+				"type":    "TextBlock",
+				"text":    "✔️ 해결된 업데이트",
+				"weight":  "Bolder",
+				"size":    "Small",
+				"spacing": "Medium",
+			},
+		)
+		blocks = append(blocks, teamsUpdateRows(report.Resolved)...)
+	}
+	if len(report.Problems) > 0 {
+		items := []interface{}{
+			map[string]interface{}{"type": "TextBlock", "text": "⚠️ " + problemsTitle(report.Problems), "weight": "Bolder", "size": "Small", "color": "Warning"},
+		}
+		for _, line := range problemLines(report.Problems) {
+			items = append(items, map[string]interface{}{"type": "TextBlock", "text": line, "wrap": true, "size": "Small", "spacing": "None"})
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"type":    "Container",
+			"style":   "warning",
+			"spacing": "Medium",
+			"items":   items,
+		})
+	}
+	return blocks
+}
+
+func teamsUpdateRows(updates []renovate.UpdateInfo) []interface{} {
 	// Construct the rows
 	var rows []interface{}
 	for _, u := range updates {
@@ -46,6 +166,20 @@ func (n *TeamsNotifier) Notify(ctx context.Context, repo string, updates []renov
 			updateTypeText = "✅ " + u.UpdateType
 		}
 
+		name := u.DepName
+		if link := u.Link(); link != "" {
+			name = fmt.Sprintf("[%s](%s)", u.DepName, link)
+		}
+		nameItems := []interface{}{
+			map[string]interface{}{"type": "TextBlock", "text": name, "wrap": true, "size": "Small"},
+		}
+		if age := releaseAge(u); age != "" {
+			nameItems = append(nameItems, map[string]interface{}{"type": "TextBlock", "text": age, "size": "Small", "isSubtle": true, "spacing": "None"})
+		}
+		if note := securityNote(u); note != "" {
+			nameItems = append(nameItems, map[string]interface{}{"type": "TextBlock", "text": "🛡️ " + note, "wrap": true, "size": "Small", "color": "Attention", "weight": "Bolder", "spacing": "None"})
+		}
+
 		row := map[string]interface{}{
 			"type":      "ColumnSet",
 			"separator": true,
@@ -53,9 +187,7 @@ func (n *TeamsNotifier) Notify(ctx context.Context, repo string, updates []renov
 				map[string]interface{}{
 					"type":  "Column",
 					"width": "stretch",
-					"items": []interface{}{
-						map[string]interface{}{"type": "TextBlock", "text": u.DepName, "wrap": true, "size": "Small"},
-					},
+					"items": nameItems,
 				},
 				map[string]interface{}{
 					"type":  "Column",
@@ -75,58 +207,52 @@ func (n *TeamsNotifier) Notify(ctx context.Context, repo string, updates []renov
 		}
 		rows = append(rows, row)
 	}
+	return rows
+}
 
-	cardBody := []interface{}{
-		map[string]interface{}{
-			"type":   "TextBlock",
-			"text":   "📢 Dependency Updates",
-			"weight": "Bolder",
-			"size":   "Large",
-			"color":  "Accent",
-		},
-		map[string]interface{}{
-			"type":     "TextBlock",
-			"text":     fmt.Sprintf("새로운 의존성 업데이트가 감지되었습니다. (%s)", repo),
-			"isSubtle": true,
-			"wrap":     true,
-		},
-		map[string]interface{}{
-			"type":  "Container",
-			"style": "emphasis",
-			"items": []interface{}{
-				map[string]interface{}{
-					"type": "ColumnSet",
-					"columns": []interface{}{
-						map[string]interface{}{
-							"type":  "Column",
-							"width": "stretch",
-							"items": []interface{}{
-								map[string]interface{}{"type": "TextBlock", "text": "📦 패키지명", "weight": "Bolder", "size": "Small"},
-							},
+func teamsColumnHeader() map[string]interface{} {
+	return map[string]interface{}{
+		"type":  "Container",
+		"style": "emphasis",
+		"items": []interface{}{
+			map[string]interface{}{
+				"type": "ColumnSet",
+				"columns": []interface{}{
+					map[string]interface{}{
+						"type":  "Column",
+						"width": "stretch",
+						"items": []interface{}{
+							map[string]interface{}{"type": "TextBlock", "text": "📦 패키지명", "weight": "Bolder", "size": "Small"},
 						},
-						map[string]interface{}{
-							"type":  "Column",
-							"width": "auto",
-							"items": []interface{}{
-								map[string]interface{}{"type": "TextBlock", "text": "버전 변경", "weight": "Bolder", "size": "Small"},
-							},
+					},
+					map[string]interface{}{
+						"type":  "Column",
+						"width": "auto",
+						"items": []interface{}{
+							map[string]interface{}{"type": "TextBlock", "text": "버전 변경", "weight": "Bolder", "size": "Small"},
 						},
-						map[string]interface{}{
-							"type":  "Column",
-							"width": "60px",
-							"items": []interface{}{
-								map[string]interface{}{"type": "TextBlock", "text": "유형", "weight": "Bolder", "size": "Small", "horizontalAlignment": "Right"},
-							},
+					},
+					map[string]interface{}{
+						"type":  "Column",
+						"width": "60px",
+						"items": []interface{}{
+							map[string]interface{}{"type": "TextBlock", "text": "유형", "weight": "Bolder", "size": "Small", "horizontalAlignment": "Right"},
 						},
 					},
 				},
 			},
 		},
-		map[string]interface{}{
-			"type":  "Container",
-			"id":    "UpdateListContainer",
-			"items": rows,
-		},
+	}
+}
+
+func (n *TeamsNotifier) send(ctx context.Context, cardBody []interface{}, repoURL string) error {
+	actions := []interface{}{}
+	if repoURL != "" {
+		actions = append(actions, map[string]interface{}{
+			"type":  "Action.OpenUrl",
+			"title": "🔗 저장소 바로가기",
+			"url":   repoURL,
+		})
 	}
 
 	payload := map[string]interface{}{
@@ -140,13 +266,7 @@ func (n *TeamsNotifier) Notify(ctx context.Context, repo string, updates []renov
 					"type":    "AdaptiveCard",
 					"version": "1.5",
 					"body":    cardBody,
-					"actions": []interface{}{
-						map[string]interface{}{
-							"type":  "Action.OpenUrl",
-							"title": "🔗 GitHub Repo 바로가기",
-							"url":   fmt.Sprintf("https://github.com/%s", repo),
-						},
-					},
+					"actions": actions,
 					"msteams": map[string]interface{}{
 						"width": "Full",
 					},
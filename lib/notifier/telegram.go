@@ -11,6 +11,8 @@ import (
 	"github.com/snowmerak/renovates/lib/renovate"
 )
 
+const telegramMaxMessageLength = 4096
+
 type TelegramNotifier struct {
 	Token  string
 	ChatID string
@@ -23,20 +25,98 @@ func NewTelegramNotifier(token, chatID string) *TelegramNotifier {
 	}
 }
 
-func (n *TelegramNotifier) Notify(ctx context.Context, repo string, updates []renovate.UpdateInfo) error {
+func (n *TelegramNotifier) Notify(ctx context.Context, report Report) error {
+	if n.Token == "" || n.ChatID == "" {
+		return nil
+	}
+
+	if report.Empty() {
+		return nil
+	}
+
+	header := fmt.Sprintf("📢 *Dependency Updates for %s*\n\n", report.Repo)
+	return n.sendParts(ctx, header, telegramReportParts(report))
+}
+
+func (n *TelegramNotifier) NotifyDigest(ctx context.Context, reports []Report) error {
 	if n.Token == "" || n.ChatID == "" {
 		return nil
 	}
 
-	if len(updates) == 0 {
+	summary := Summarize(reports)
+	if summary.Repos == 0 {
 		return nil
 	}
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("📢 *Dependency Updates for %s*\n\n", repo))
+	header := fmt.Sprintf("📢 *Dependency Updates Digest*\n%d update(s) in %d repositories (%s)\n", summary.Total, summary.Repos, summary.Totals())
+	var parts []string
+	for _, r := range pendingReports(reports) {
+		parts = append(parts, fmt.Sprintf("\n📁 *%s*\n", r.Repo))
+		parts = append(parts, telegramReportParts(r)...)
+	}
+
+	return n.sendParts(ctx, header, parts)
+}
 
+// sendParts sends the header followed by the parts, splitting them over as
+// many messages as Telegram's length limit requires. Parts are never split,
+// so Markdown entities stay balanced; a part that is too long on its own is
+// cut and sent as preformatted text.
+func (n *TelegramNotifier) sendParts(ctx context.Context, header string, parts []string) error {
+	message := header
+	for _, part := range parts {
+		if len(part) > telegramMaxMessageLength {
+			part = telegramPre(part, telegramMaxMessageLength)
+		}
+		if len(message)+len(part) > telegramMaxMessageLength {
+			if err := n.send(ctx, message); err != nil {
+				return err
+			}
+			message = ""
+		}
+		message += part
+	}
+
+	return n.send(ctx, message)
+}
+
+// telegramReportParts formats a report as message parts of a few lines each.
+func telegramReportParts(report Report) []string {
+	parts := telegramUpdateParts(report.Updates)
+	if len(report.Resolved) > 0 {
+		parts = append(parts, "✔️ _Resolved:_\n")
+		parts = append(parts, telegramUpdateParts(report.Resolved)...)
+	}
+	if len(report.Problems) > 0 {
+		// Problem messages are free text, so keep them in pre blocks where
+		// Markdown entities are not parsed
+		parts = append(parts, fmt.Sprintf("⚠️ _%s:_\n", problemsTitle(report.Problems)))
+		for _, line := range problemLines(report.Problems) {
+			parts = append(parts, telegramPre(line, telegramMaxMessageLength))
+		}
+	}
+	return parts
+}
+
+// telegramPre wraps text in a pre block of at most limit bytes.
+func telegramPre(text string, limit int) string {
+	const open, close = "```\n", "\n```\n"
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "`", "'"), "\n")
+	if room := limit - len(open) - len(close); len(text) > room {
+		text = truncateUTF8(text, room-len("…")) + "…"
+	}
+	return open + text + close
+}
+
+func telegramUpdateParts(updates []renovate.UpdateInfo) []string {
+	var parts []string
 	for _, u := range updates {
-		sb.WriteString(fmt.Sprintf("📦 *%s*", u.DepName))
+		var sb strings.Builder
+		if link := u.Link(); link != "" {
+			sb.WriteString(fmt.Sprintf("📦 [%s](%s)", u.DepName, link))
+		} else {
+			sb.WriteString(fmt.Sprintf("📦 *%s*", u.DepName))
+		}
 		if u.PackageFile != "" {
 			sb.WriteString(fmt.Sprintf(" in `%s`", u.PackageFile))
 		}
@@ -45,13 +125,23 @@ func (n *TelegramNotifier) Notify(ctx context.Context, repo string, updates []re
 		if u.UpdateType != "" {
 			sb.WriteString(fmt.Sprintf(" \\[%s]", u.UpdateType))
 		}
+		if age := releaseAge(u); age != "" {
+			sb.WriteString(fmt.Sprintf(" _(%s)_", age))
+		}
 		sb.WriteString("\n")
+		if note := securityNote(u); note != "" {
+			sb.WriteString(fmt.Sprintf("   🛡️ _Security fix_ (%s)\n", note))
+		}
+		parts = append(parts, sb.String())
 	}
+	return parts
+}
 
+func (n *TelegramNotifier) send(ctx context.Context, text string) error {
 	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", n.Token)
 	payload := map[string]interface{}{
 		"chat_id":    n.ChatID,
-		"text":       sb.String(),
+		"text":       text,
 		"parse_mode": "Markdown",
 	}
 
@@ -19,20 +19,56 @@ func NewWebhookNotifier(url string) *WebhookNotifier {
 }
 
 type webhookPayload struct {
-	Repo    string                `json:"repo"`
-	Updates []renovate.UpdateInfo `json:"updates"`
+	Repo     string                `json:"repo"`
+	Updates  []renovate.UpdateInfo `json:"updates"`
+	Resolved []renovate.UpdateInfo `json:"resolved,omitempty"`
+	Problems []renovate.Problem    `json:"problems,omitempty"`
 }
 
-func (n *WebhookNotifier) Notify(ctx context.Context, repo string, updates []renovate.UpdateInfo) error {
+type webhookDigestPayload struct {
+	Total    int              `json:"total"`
+	Resolved int              `json:"resolved,omitempty"`
+	ByType   map[string]int   `json:"byType"`
+	Repos    []webhookPayload `json:"repos"`
+}
+
+func newWebhookPayload(report Report) webhookPayload {
+	return webhookPayload{
+		Repo:     report.Repo,
+		Updates:  report.Updates,
+		Resolved: report.Resolved,
+		Problems: report.Problems,
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, report Report) error {
+	if n.URL == "" {
+		return nil
+	}
+
+	return n.send(ctx, newWebhookPayload(report))
+}
+
+func (n *WebhookNotifier) NotifyDigest(ctx context.Context, reports []Report) error {
 	if n.URL == "" {
 		return nil
 	}
 
-	payload := webhookPayload{
-		Repo:    repo,
-		Updates: updates,
+	summary := Summarize(reports)
+	payload := webhookDigestPayload{
+		Total:    summary.Total,
+		Resolved: summary.Resolved,
+		ByType:   summary.ByType,
+		Repos:    make([]webhookPayload, 0, len(reports)),
 	}
+	for _, r := range reports {
+		payload.Repos = append(payload.Repos, newWebhookPayload(r))
+	}
+
+	return n.send(ctx, payload)
+}
 
+func (n *WebhookNotifier) send(ctx context.Context, payload interface{}) error {
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal webhook payload: %w", err)
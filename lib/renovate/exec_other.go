@@ -0,0 +1,13 @@
+//go:build !unix
+
+package renovate
+
+import "os/exec"
+
+// setCancel kills Renovate on cancellation; there is no graceful
+// termination signal on this platform.
+func setCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package renovate
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCancel runs Renovate in its own process group and terminates the whole
+// group on cancellation, so child processes (git, package managers) do not
+// keep running or hold the output pipes open.
+func setCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+}
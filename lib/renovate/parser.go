@@ -1,10 +1,12 @@
 package renovate
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
-	"strings"
+	"time"
 )
 
 type UpdateInfo struct {
@@ -13,6 +15,48 @@ type UpdateInfo struct {
 	NewVersion     string `json:"newVersion"`
 	UpdateType     string `json:"updateType"`
 	PackageFile    string `json:"packageFile"`
+
+	Datasource       string `json:"datasource,omitempty"`
+	Manager          string `json:"manager,omitempty"`
+	DepType          string `json:"depType,omitempty"`
+	ReleaseTimestamp string `json:"releaseTimestamp,omitempty"`
+	SourceURL        string `json:"sourceUrl,omitempty"`
+	Homepage         string `json:"homepage,omitempty"`
+	ChangelogURL     string `json:"changelogUrl,omitempty"`
+
+	IsVulnerabilityAlert  bool     `json:"isVulnerabilityAlert,omitempty"`
+	VulnerabilitySeverity string   `json:"vulnerabilitySeverity,omitempty"`
+	Vulnerabilities       []string `json:"vulnerabilities,omitempty"`
+}
+
+// Key identifies an update across runs.
+func (u UpdateInfo) Key() string {
+	return fmt.Sprintf("%s|%s|%s", u.DepName, u.PackageFile, u.NewVersion)
+}
+
+// ReleaseTime parses ReleaseTimestamp, reporting false when it is missing or malformed.
+func (u UpdateInfo) ReleaseTime() (time.Time, bool) {
+	if u.ReleaseTimestamp == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, u.ReleaseTimestamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Link returns the most useful page for reviewing the update: the
+// changelog, the source repository or the homepage.
+func (u UpdateInfo) Link() string {
+	switch {
+	case u.ChangelogURL != "":
+		return u.ChangelogURL
+	case u.SourceURL != "":
+		return u.SourceURL
+	default:
+		return u.Homepage
+	}
 }
 
 type upgrade struct {
@@ -21,6 +65,18 @@ type upgrade struct {
 	NewVersion     string `json:"newVersion"`
 	UpdateType     string `json:"updateType"`
 	PackageFile    string `json:"packageFile"`
+
+	Datasource       string `json:"datasource"`
+	Manager          string `json:"manager"`
+	DepType          string `json:"depType"`
+	ReleaseTimestamp string `json:"releaseTimestamp"`
+	SourceURL        string `json:"sourceUrl"`
+	Homepage         string `json:"homepage"`
+	ChangelogURL     string `json:"changelogUrl"`
+
+	IsVulnerabilityAlert  bool     `json:"isVulnerabilityAlert"`
+	VulnerabilitySeverity string   `json:"vulnerabilitySeverity"`
+	PrBodyNotes           []string `json:"prBodyNotes"`
 }
 
 type branchInfo struct {
@@ -29,14 +85,28 @@ type branchInfo struct {
 }
 
 type packageFileUpdate struct {
-	NewVersion string `json:"newVersion"`
-	UpdateType string `json:"updateType"`
+	NewVersion       string `json:"newVersion"`
+	UpdateType       string `json:"updateType"`
+	ReleaseTimestamp string `json:"releaseTimestamp"`
+
+	IsVulnerabilityAlert  bool     `json:"isVulnerabilityAlert"`
+	VulnerabilitySeverity string   `json:"vulnerabilitySeverity"`
+	PrBodyNotes           []string `json:"prBodyNotes"`
 }
 
 type packageFileDep struct {
 	DepName        string              `json:"depName"`
 	CurrentVersion string              `json:"currentVersion"`
 	Updates        []packageFileUpdate `json:"updates"`
+
+	Datasource   string `json:"datasource"`
+	DepType      string `json:"depType"`
+	SourceURL    string `json:"sourceUrl"`
+	Homepage     string `json:"homepage"`
+	ChangelogURL string `json:"changelogUrl"`
+
+	IsVulnerabilityAlert  bool   `json:"isVulnerabilityAlert"`
+	VulnerabilitySeverity string `json:"vulnerabilitySeverity"`
 }
 
 type packageFile struct {
@@ -44,63 +114,215 @@ type packageFile struct {
 	Deps        []packageFileDep `json:"deps"`
 }
 
+// logEntry holds the fields every log line is inspected for. Payloads are
+// decoded separately, so a field with an unexpected shape never hides a
+// warning or error.
 type logEntry struct {
-	Msg                 string                   `json:"msg"`
-	BranchesInformation []branchInfo             `json:"branchesInformation"`
-	Config              map[string][]packageFile `json:"config"`
+	Msg         string          `json:"msg"`
+	Level       logLevel        `json:"level"`
+	DepName     string          `json:"depName"`
+	PackageName string          `json:"packageName"`
+	Err         json.RawMessage `json:"err"`
 }
 
-func ParseUpdates(output []byte) []UpdateInfo {
-	lines := strings.Split(string(output), "\n")
-	// Key: DepName + PackageFile + NewVersion
-	updatesMap := make(map[string]UpdateInfo)
+type branchesEntry struct {
+	BranchesInformation []branchInfo `json:"branchesInformation"`
+}
 
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
+type packageFilesEntry struct {
+	Config map[string][]packageFile `json:"config"`
+}
+
+type alertsEntry struct {
+	VulnerabilityAlerts []vulnerabilityAlert `json:"vulnerabilityAlerts"`
+	Alerts              []vulnerabilityAlert `json:"alerts"`
+}
+
+// Result is everything extracted from the Renovate log of one repository.
+type Result struct {
+	Updates  []UpdateInfo `json:"updates"`
+	Problems []Problem    `json:"problems,omitempty"`
+}
+
+// maxLineSize bounds the memory used for a single log line. Lines above it
+// are skipped and reported as a problem. It is a variable so tests can lower
+// it.
+var maxLineSize = 256 << 20
+
+// Lines are buffered in memory; a buffer grown by a large line is released
+// afterwards instead of being kept for the rest of the log.
+const maxRetainedLineBuffer = 1 << 20
+
+// Parser extracts updates and problems from Renovate's JSON log as it is
+// written, line by line, so the whole log never has to be held in memory.
+type Parser struct {
+	line     []byte
+	overflow bool
+	skipped  int
+
+	updates  map[string]UpdateInfo
+	alerts   []vulnerabilityAlert
+	problems *problemSet
+}
+
+func NewParser() *Parser {
+	return &Parser{
+		updates:  make(map[string]UpdateInfo),
+		problems: newProblemSet(),
+	}
+}
+
+// Write consumes log output. It never fails, so it can be used as the
+// output of a command directly.
+func (p *Parser) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		chunk := b
+		if i >= 0 {
+			chunk = b[:i]
 		}
 
-		var entry logEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
+		if !p.overflow {
+			if len(p.line)+len(chunk) > maxLineSize {
+				p.overflow = true
+				p.line = nil
+			} else {
+				p.line = append(p.line, chunk...)
+			}
 		}
 
-		if entry.Msg == "branches info extended" && len(entry.BranchesInformation) > 0 {
-			for _, branch := range entry.BranchesInformation {
-				for _, upgrade := range branch.Upgrades {
-					key := fmt.Sprintf("%s|%s|%s", upgrade.DepName, upgrade.PackageFile, upgrade.NewVersion)
-					updatesMap[key] = UpdateInfo{
-						DepName:        upgrade.DepName,
-						CurrentVersion: upgrade.CurrentVersion,
-						NewVersion:     upgrade.NewVersion,
-						UpdateType:     upgrade.UpdateType,
-						PackageFile:    upgrade.PackageFile,
-					}
-				}
+		if i < 0 {
+			break
+		}
+		p.endLine()
+		b = b[i+1:]
+	}
+	return n, nil
+}
+
+func (p *Parser) endLine() {
+	if p.overflow {
+		p.skipped++
+		p.overflow = false
+	} else {
+		p.parseLine(p.line)
+	}
+
+	if cap(p.line) > maxRetainedLineBuffer {
+		p.line = nil
+	} else {
+		p.line = p.line[:0]
+	}
+}
+
+func (p *Parser) parseLine(line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+
+	var entry logEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return
+	}
+
+	if entry.Level >= levelWarn {
+		p.problems.add(entry)
+	}
+
+	if bytes.Contains(line, []byte(`"vulnerabilityAlerts"`)) || bytes.Contains(line, []byte(`"alerts"`)) {
+		var alerts alertsEntry
+		if err := json.Unmarshal(line, &alerts); err == nil {
+			p.alerts = append(p.alerts, alerts.VulnerabilityAlerts...)
+			p.alerts = append(p.alerts, alerts.Alerts...)
+		}
+	}
+
+	switch entry.Msg {
+	case "branches info extended":
+		var branches branchesEntry
+		if err := json.Unmarshal(line, &branches); err == nil {
+			p.addBranches(branches.BranchesInformation)
+		}
+	case "packageFiles with updates":
+		var packageFiles packageFilesEntry
+		if err := json.Unmarshal(line, &packageFiles); err == nil {
+			p.addPackageFiles(packageFiles.Config)
+		}
+	}
+}
+
+func (p *Parser) addBranches(branches []branchInfo) {
+	for _, branch := range branches {
+		for _, upgrade := range branch.Upgrades {
+			u := UpdateInfo{
+				DepName:        upgrade.DepName,
+				CurrentVersion: upgrade.CurrentVersion,
+				NewVersion:     upgrade.NewVersion,
+				UpdateType:     upgrade.UpdateType,
+				PackageFile:    upgrade.PackageFile,
+
+				Datasource:       upgrade.Datasource,
+				Manager:          upgrade.Manager,
+				DepType:          upgrade.DepType,
+				ReleaseTimestamp: upgrade.ReleaseTimestamp,
+				SourceURL:        upgrade.SourceURL,
+				Homepage:         upgrade.Homepage,
+				ChangelogURL:     upgrade.ChangelogURL,
+
+				IsVulnerabilityAlert:  upgrade.IsVulnerabilityAlert,
+				VulnerabilitySeverity: normalizeSeverity(upgrade.VulnerabilitySeverity),
+				Vulnerabilities:       extractVulnerabilityIDs(upgrade.PrBodyNotes...),
 			}
-		} else if entry.Msg == "packageFiles with updates" && len(entry.Config) > 0 {
-			for _, packageFiles := range entry.Config {
-				for _, pf := range packageFiles {
-					for _, dep := range pf.Deps {
-						for _, update := range dep.Updates {
-							key := fmt.Sprintf("%s|%s|%s", dep.DepName, pf.PackageFile, update.NewVersion)
-							updatesMap[key] = UpdateInfo{
-								DepName:        dep.DepName,
-								CurrentVersion: dep.CurrentVersion,
-								NewVersion:     update.NewVersion,
-								UpdateType:     update.UpdateType,
-								PackageFile:    pf.PackageFile,
-							}
-						}
+			p.updates[u.Key()] = mergeUpdate(p.updates[u.Key()], u)
+		}
+	}
+}
+
+func (p *Parser) addPackageFiles(config map[string][]packageFile) {
+	for manager, packageFiles := range config {
+		for _, pf := range packageFiles {
+			for _, dep := range pf.Deps {
+				for _, update := range dep.Updates {
+					u := UpdateInfo{
+						DepName:        dep.DepName,
+						CurrentVersion: dep.CurrentVersion,
+						NewVersion:     update.NewVersion,
+						UpdateType:     update.UpdateType,
+						PackageFile:    pf.PackageFile,
+
+						Datasource:       dep.Datasource,
+						Manager:          manager,
+						DepType:          dep.DepType,
+						ReleaseTimestamp: update.ReleaseTimestamp,
+						SourceURL:        dep.SourceURL,
+						Homepage:         dep.Homepage,
+						ChangelogURL:     dep.ChangelogURL,
+
+						IsVulnerabilityAlert:  update.IsVulnerabilityAlert || dep.IsVulnerabilityAlert,
+						VulnerabilitySeverity: normalizeSeverity(update.VulnerabilitySeverity),
+						Vulnerabilities:       extractVulnerabilityIDs(update.PrBodyNotes...),
+					}
+					if u.VulnerabilitySeverity == "" {
+						u.VulnerabilitySeverity = normalizeSeverity(dep.VulnerabilitySeverity)
 					}
+					p.updates[u.Key()] = mergeUpdate(p.updates[u.Key()], u)
 				}
 			}
 		}
 	}
+}
+
+// Result returns everything parsed so far, including an unterminated last
+// line. It is meant to be called once the log is complete.
+func (p *Parser) Result() Result {
+	if len(p.line) > 0 || p.overflow {
+		p.endLine()
+	}
 
 	var updates []UpdateInfo
-	for _, u := range updatesMap {
-		updates = append(updates, u)
+	for _, u := range p.updates {
+		updates = append(updates, applyVulnerabilityAlerts(u, p.alerts))
 	}
 
 	sort.Slice(updates, func(i, j int) bool {
@@ -110,5 +332,62 @@ func ParseUpdates(output []byte) []UpdateInfo {
 		return updates[i].NewVersion < updates[j].NewVersion
 	})
 
-	return updates
+	problems := p.problems.list
+	if p.skipped > 0 {
+		problems = append(problems, Problem{
+			Level:   levelWarn.String(),
+			Message: fmt.Sprintf("%d log line(s) larger than %d MiB were skipped", p.skipped, maxLineSize>>20),
+		})
+	}
+
+	return Result{Updates: updates, Problems: problems}
+}
+
+func ParseUpdates(output []byte) []UpdateInfo {
+	return Parse(output).Updates
+}
+
+func Parse(output []byte) Result {
+	p := NewParser()
+	p.Write(output)
+	return p.Result()
+}
+
+// ParseReader parses a Renovate JSON log read from r.
+func ParseReader(r io.Reader) (Result, error) {
+	p := NewParser()
+	if _, err := io.Copy(p, r); err != nil {
+		return Result{}, fmt.Errorf("failed to read log: %w", err)
+	}
+	return p.Result(), nil
+}
+
+// mergeUpdate combines two entries for the same update, keeping the
+// metadata and vulnerability details from whichever log message reported them.
+func mergeUpdate(existing, u UpdateInfo) UpdateInfo {
+	if existing.DepName == "" {
+		return u
+	}
+
+	for _, f := range []struct{ dst, src *string }{
+		{&u.Datasource, &existing.Datasource},
+		{&u.Manager, &existing.Manager},
+		{&u.DepType, &existing.DepType},
+		{&u.ReleaseTimestamp, &existing.ReleaseTimestamp},
+		{&u.SourceURL, &existing.SourceURL},
+		{&u.Homepage, &existing.Homepage},
+		{&u.ChangelogURL, &existing.ChangelogURL},
+	} {
+		if *f.dst == "" {
+			*f.dst = *f.src
+		}
+	}
+
+	u.IsVulnerabilityAlert = u.IsVulnerabilityAlert || existing.IsVulnerabilityAlert
+	if u.VulnerabilitySeverity == "" {
+		u.VulnerabilitySeverity = existing.VulnerabilitySeverity
+	}
+	u.Vulnerabilities = mergeIDs(existing.Vulnerabilities, u.Vulnerabilities)
+
+	return u
 }
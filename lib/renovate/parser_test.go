@@ -0,0 +1,193 @@
+package renovate
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want []Problem
+	}{
+		{
+			name: "error as string",
+			log:  `{"level":40,"msg":"registry auth failed","depName":"x","err":"401 Unauthorized"}`,
+			want: []Problem{{Level: "warn", Message: "registry auth failed", DepName: "x", Error: "401 Unauthorized"}},
+		},
+		{
+			name: "error as object",
+			log:  `{"level":50,"msg":"lookup failed","packageName":"y","err":{"message":"ENOTFOUND","stack":"..."}}`,
+			want: []Problem{{Level: "error", Message: "lookup failed", DepName: "y", Error: "ENOTFOUND"}},
+		},
+		{
+			name: "config with an unexpected shape",
+			log:  `{"level":50,"msg":"Repository has invalid config","config":{"extends":"bad"}}`,
+			want: []Problem{{Level: "error", Message: "Repository has invalid config"}},
+		},
+		{
+			name: "level name",
+			log:  `{"level":"warn","msg":"Cannot access vulnerability alerts","alerts":"denied"}`,
+			want: []Problem{{Level: "warn", Message: "Cannot access vulnerability alerts"}},
+		},
+		{
+			name: "repeated problems are reported once",
+			log:  `{"level":40,"msg":"a"}` + "\n" + `{"level":40,"msg":"a"}` + "\n" + `{"level":30,"msg":"info"}`,
+			want: []Problem{{Level: "warn", Message: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse([]byte(tt.log)).Problems
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse() problems = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Parse() problem %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseUpdatesIgnoresMismatchedPayload(t *testing.T) {
+	log := `{"level":20,"msg":"packageFiles with updates","config":{"gomod":"unexpected"}}
+{"level":20,"msg":"branches info extended","branchesInformation":[{"upgrades":[{"depName":"x","newVersion":"2.0.0","updateType":"major","packageFile":"go.mod"}]}]}`
+
+	result := Parse([]byte(log))
+	if len(result.Updates) != 1 || result.Updates[0].DepName != "x" {
+		t.Errorf("Parse() updates = %+v, want the update of x", result.Updates)
+	}
+}
+
+func TestParseFixture(t *testing.T) {
+	result := Parse(readFixture(t))
+
+	var got []string
+	for _, u := range result.Updates {
+		got = append(got, u.DepName+" "+u.CurrentVersion+" -> "+u.NewVersion+" "+strings.Join(u.Vulnerabilities, ","))
+	}
+	want := []string{
+		"golang.org/x/net v0.20.0 -> v0.30.0 ",
+		"lodash 4.17.20 -> 4.17.21 GHSA-aaaa-bbbb-cccc",
+		"react 17.0.2 -> 18.3.1 CVE-2024-12345",
+		"typescript 5.4.5 -> 5.6.3 ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() updates = %q, want %q", got, want)
+	}
+
+	wantProblems := []Problem{
+		{Level: "warn", Message: "Failed to look up npm package", DepName: "private-lib", Error: "401 Unauthorized"},
+		{Level: "error", Message: "Repository has invalid config", Error: "Invalid extends preset"},
+	}
+	if !reflect.DeepEqual(result.Problems, wantProblems) {
+		t.Errorf("Parse() problems = %+v, want %+v", result.Problems, wantProblems)
+	}
+}
+
+func TestParserChunkBoundaries(t *testing.T) {
+	log := readFixture(t)
+	want := Parse(log)
+
+	for _, size := range []int{1, 2, 3, 7, 64, 1000, len(log)} {
+		got := parseChunks(log, size)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("chunks of %d bytes: Result() = %+v, want %+v", size, got, want)
+		}
+	}
+
+	got, err := ParseReader(iotest.OneByteReader(bytes.NewReader(log)))
+	if err != nil {
+		t.Fatalf("ParseReader(): %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseReader() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParserUnterminatedLastLine(t *testing.T) {
+	log := readFixture(t)
+	want := Parse(log)
+
+	unterminated := bytes.TrimSuffix(log, []byte("\n"))
+	if got := Parse(unterminated); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+	if got := parseChunks(unterminated, 7); !reflect.DeepEqual(got, want) {
+		t.Errorf("chunks of 7 bytes: Result() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParserLongLine(t *testing.T) {
+	log := readFixture(t)
+	want := Parse(log)
+
+	lines := bytes.SplitAfter(log, []byte("\n"))
+	longest := 0
+	for _, line := range lines {
+		longest = max(longest, len(line))
+	}
+	defer func(size int) { maxLineSize = size }(maxLineSize)
+	maxLineSize = longest
+
+	// The skipped line is a problem itself, but must not be reported as one
+	long := []byte(`{"level":50,"msg":"` + strings.Repeat("x", longest) + `"}`)
+	skipped := Problem{Level: "warn", Message: "1 log line(s) larger than 0 MiB were skipped"}
+
+	tests := []struct {
+		name string
+		log  []byte
+	}{
+		{
+			name: "in the middle",
+			log:  bytes.Join([][]byte{lines[0], long, []byte("\n"), bytes.Join(lines[1:], nil)}, nil),
+		},
+		{
+			name: "unterminated at the end",
+			log:  append(append([]byte{}, log...), long...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, size := range []int{100, len(tt.log)} {
+				got := parseChunks(tt.log, size)
+				if !reflect.DeepEqual(got.Updates, want.Updates) {
+					t.Errorf("chunks of %d bytes: updates = %+v, want %+v", size, got.Updates, want.Updates)
+				}
+				wantProblems := append(append([]Problem{}, want.Problems...), skipped)
+				if !reflect.DeepEqual(got.Problems, wantProblems) {
+					t.Errorf("chunks of %d bytes: problems = %+v, want %+v", size, got.Problems, wantProblems)
+				}
+			}
+		})
+	}
+}
+
+func readFixture(t *testing.T) []byte {
+	t.Helper()
+	log, err := os.ReadFile("testdata/renovate.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log
+}
+
+// parseChunks feeds log to a Parser in writes of at most size bytes.
+func parseChunks(log []byte, size int) Result {
+	p := NewParser()
+	for len(log) > 0 {
+		n := min(size, len(log))
+		p.Write(log[:n])
+		log = log[n:]
+	}
+	return p.Result()
+}
@@ -0,0 +1,147 @@
+package renovate
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Bunyan log levels used by Renovate's JSON output.
+const (
+	levelTrace logLevel = 10
+	levelDebug logLevel = 20
+	levelInfo  logLevel = 30
+	levelWarn  logLevel = 40
+	levelError logLevel = 50
+	levelFatal logLevel = 60
+)
+
+var levelNames = map[string]logLevel{
+	"trace": levelTrace,
+	"debug": levelDebug,
+	"info":  levelInfo,
+	"warn":  levelWarn,
+	"error": levelError,
+	"fatal": levelFatal,
+}
+
+// logLevel accepts both numeric bunyan levels and level names.
+type logLevel int
+
+func (l *logLevel) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*l = logLevel(n)
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	*l = levelNames[strings.ToLower(name)]
+	return nil
+}
+
+func (l logLevel) String() string {
+	switch {
+	case l >= levelFatal:
+		return "fatal"
+	case l >= levelError:
+		return "error"
+	case l >= levelWarn:
+		return "warn"
+	case l >= levelInfo:
+		return "info"
+	case l >= levelDebug:
+		return "debug"
+	default:
+		return "trace"
+	}
+}
+
+// errorMessage returns the message of a logged error, which Renovate writes
+// either as a serialized error object or as a plain string.
+func errorMessage(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	var message string
+	if err := json.Unmarshal(raw, &message); err == nil {
+		return message
+	}
+
+	var obj struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil && obj.Message != "" {
+		return obj.Message
+	}
+	if len(raw) > maxRawErrorSize {
+		return string(raw[:maxRawErrorSize]) + "…"
+	}
+	return string(raw)
+}
+
+// maxRawErrorSize bounds errors that have no message and are kept as JSON.
+const maxRawErrorSize = 1000
+
+// Problem is a warning or error reported by Renovate, such as a lookup
+// failure, a registry authentication error or an invalid config.
+type Problem struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	DepName string `json:"depName,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// IsError reports whether the problem is an error rather than a warning.
+func (p Problem) IsError() bool {
+	return p.Level == "error" || p.Level == "fatal"
+}
+
+// Key identifies a problem across runs. The error text is left out since it
+// often carries request IDs or timestamps.
+func (p Problem) Key() string {
+	return p.Level + "|" + p.DepName + "|" + p.Message
+}
+
+func (p Problem) String() string {
+	s := p.Message
+	if p.DepName != "" {
+		s += " (" + p.DepName + ")"
+	}
+	if p.Error != "" {
+		s += ": " + p.Error
+	}
+	return s
+}
+
+// problemSet collects problems in log order, dropping repeated messages.
+type problemSet struct {
+	seen map[string]bool
+	list []Problem
+}
+
+func newProblemSet() *problemSet {
+	return &problemSet{seen: make(map[string]bool)}
+}
+
+func (s *problemSet) add(entry logEntry) {
+	p := Problem{
+		Level:   entry.Level.String(),
+		Message: entry.Msg,
+		DepName: entry.DepName,
+	}
+	if p.DepName == "" {
+		p.DepName = entry.PackageName
+	}
+	p.Error = errorMessage(entry.Err)
+
+	key := p.Level + "|" + p.String()
+	if s.seen[key] {
+		return
+	}
+	s.seen[key] = true
+	s.list = append(s.list, p)
+}
@@ -4,40 +4,217 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/pelletier/go-toml/v2"
+	"github.com/snowmerak/renovates/lib/githubapp"
+	"golang.org/x/oauth2"
 )
 
 type NotifierConfig struct {
-	Type   string `toml:"type"`
-	URL    string `toml:"url"`
-	Token  string `toml:"token"`
-	ChatID string `toml:"chat_id"`
+	Type    string `toml:"type"`
+	URL     string `toml:"url"`
+	Token   string `toml:"token"`
+	ChatID  string `toml:"chat_id"`
+	Channel string `toml:"channel"`
+	Digest  bool   `toml:"digest"`
+
+	// SMTP settings for the email notifier
+	Host     string   `toml:"host"`
+	Port     int      `toml:"port"`
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	From     string   `toml:"from"`
+	To       []string `toml:"to"`
+	TLS      string   `toml:"tls"`
 }
 
-type DiscoveryConfig struct {
-	Enabled  bool     `toml:"enabled"`
+// GitHubAppConfig authenticates as a GitHub App installation instead of with
+// a static token.
+type GitHubAppConfig struct {
+	AppID          int64  `toml:"app_id"`
+	InstallationID int64  `toml:"installation_id"`
+	PrivateKey     string `toml:"private_key"` // PEM contents
+	PrivateKeyPath string `toml:"private_key_path"`
+}
+
+func (a GitHubAppConfig) enabled() bool {
+	return a.AppID != 0
+}
+
+// DiscoveryFilters select the repositories listed by a discoverer.
+type DiscoveryFilters struct {
 	Owner    string   `toml:"owner"`
 	Topics   []string `toml:"topics"`
 	Includes []string `toml:"includes"`
 	Excludes []string `toml:"excludes"`
+
+	SkipArchived     bool     `toml:"skip_archived"`
+	SkipForks        bool     `toml:"skip_forks"`
+	SkipEmpty        bool     `toml:"skip_empty"` // Repositories without a default branch
+	Visibility       []string `toml:"visibility"` // public, private or internal
+	Languages        []string `toml:"languages"`  // Primary language, case-insensitive
+	PushedWithinDays int      `toml:"pushed_within_days"`
+
+	// Manifests keeps only repositories containing one of these files,
+	// looked up in the git tree (default) or with code search.
+	Manifests      []string `toml:"manifests"`
+	ManifestLookup string   `toml:"manifest_lookup"`
+}
+
+type DiscoveryConfig struct {
+	Enabled bool `toml:"enabled"`
+	DiscoveryFilters
+
+	// Sources replace the filters above with several discovery sources,
+	// each with its own platform settings.
+	Sources []DiscoverySource `toml:"sources"`
+
+	Cache DiscoveryCacheConfig `toml:"cache"`
+}
+
+// DiscoveryCacheConfig caches discovery API responses on disk. Responses
+// younger than TTL are reused as is, older ones are revalidated.
+type DiscoveryCacheConfig struct {
+	Enabled bool     `toml:"enabled"`
+	Path    string   `toml:"path"`
+	TTL     Duration `toml:"ttl"`
+}
+
+// DiscoverySource is a set of repositories discovered on one platform.
+// Empty platform settings fall back to the top-level ones.
+type DiscoverySource struct {
+	Name     string `toml:"name"`
+	Platform string `toml:"platform"`
+	Token    string `toml:"token"`
+	Endpoint string `toml:"endpoint"`
+
+	GitHubApp GitHubAppConfig `toml:"github_app"`
+	DiscoveryFilters
+}
+
+type StateConfig struct {
+	Enabled         bool   `toml:"enabled"`
+	Path            string `toml:"path"`
+	OnlyNew         bool   `toml:"only_new"`
+	IncludeResolved bool   `toml:"include_resolved"`
+	// OnlyNewProblems reports problems only on the first run they appear in.
+	// Off by default so that a broken scan is reported until it is fixed.
+	OnlyNewProblems bool `toml:"only_new_problems"`
+}
+
+// LogsConfig keeps the raw Renovate log of every repository and run.
+type LogsConfig struct {
+	Enabled bool     `toml:"enabled"`
+	Path    string   `toml:"path"`
+	Gzip    bool     `toml:"gzip"`
+	MaxRuns int      `toml:"max_runs"` // Runs to keep, including the current one
+	MaxAge  Duration `toml:"max_age"`
+}
+
+type DaemonConfig struct {
+	Schedule   string   `toml:"schedule"`
+	Jitter     Duration `toml:"jitter"`
+	Timezone   string   `toml:"timezone"`
+	RunOnStart bool     `toml:"run_on_start"`
+}
+
+// RetryConfig retries Renovate runs that failed for transient reasons:
+// timeouts, network and rate limit errors, and the listed exit codes.
+type RetryConfig struct {
+	Attempts   int      `toml:"attempts"` // Including the first run, defaults to 1
+	Backoff    Duration `toml:"backoff"`  // Doubled after every attempt
+	MaxBackoff Duration `toml:"max_backoff"`
+	ExitCodes  []int    `toml:"exit_codes"`
+}
+
+type ServerConfig struct {
+	Addr  string `toml:"addr"`
+	Token string `toml:"token"`
+}
+
+// DryRun is Renovate's dry run mode. Off really creates branches and pull
+// requests.
+type DryRun string
+
+const (
+	DryRunExtract DryRun = "extract"
+	DryRunLookup  DryRun = "lookup"
+	DryRunFull    DryRun = "full"
+	DryRunOff     DryRun = "off"
+)
+
+func (d *DryRun) UnmarshalText(text []byte) error {
+	switch s := DryRun(text); s {
+	case "true":
+		*d = DryRunFull
+	case DryRunExtract, DryRunLookup, DryRunFull, DryRunOff:
+		*d = s
+	case "false":
+		// dry_run used to be ignored, so false must not silently open PRs
+		return fmt.Errorf(`dry_run = false is ambiguous, use dry_run = "off" to open pull requests`)
+	default:
+		return fmt.Errorf("invalid dry_run %q: expected extract, lookup, full or off", s)
+	}
+	return nil
+}
+
+// RepoOverride changes Renovate settings for the repositories matching one
+// of its glob patterns (e.g. "my-org/infra-*").
+type RepoOverride struct {
+	Repos         []string `toml:"repos"`
+	DryRun        DryRun   `toml:"dry_run"`
+	Onboarding    *bool    `toml:"onboarding"`
+	RequireConfig string   `toml:"require_config"`
 }
 
 type Config struct {
 	Command       string            `toml:"command"`
 	Platform      string            `toml:"platform"`
 	Token         string            `toml:"token"`
+	GitHubApp     GitHubAppConfig   `toml:"github_app"`
 	Endpoint      string            `toml:"endpoint"`
 	LogLevel      string            `toml:"log_level"`
-	DryRun        bool              `toml:"dry_run"`
+	DryRun        DryRun            `toml:"dry_run"` // Defaults to full
 	Onboarding    bool              `toml:"onboarding"`
-	RequireConfig string            `toml:"require_config"`
+	RequireConfig string            `toml:"require_config"` // Defaults to optional
+	Overrides     []RepoOverride    `toml:"overrides"`
 	Concurrency   int               `toml:"concurrency"`
+	Timeout       Duration          `toml:"timeout"` // Per repository and attempt
+	Retry         RetryConfig       `toml:"retry"`
 	Notifiers     []NotifierConfig  `toml:"notifiers"`
 	Discovery     DiscoveryConfig   `toml:"discovery"`
+	State         StateConfig       `toml:"state"`
+	Logs          LogsConfig        `toml:"logs"`
+	Daemon        DaemonConfig      `toml:"daemon"`
+	Server        ServerConfig      `toml:"server"`
 	ExtraEnv      map[string]string `toml:"extra_env"`
+
+	// tokenSource mints GitHub App installation tokens
+	tokenSource *githubapp.TokenSource
+}
+
+// Duration is a time.Duration written as a string like "90s" or "5m" in the config.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -52,49 +229,364 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to decode config file: %w", err)
 	}
 
+	if err := cfg.setupGitHubApp(); err != nil {
+		return nil, err
+	}
+
+	for _, o := range cfg.Overrides {
+		if err := o.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &cfg, nil
+}
+
+// ForRepo returns the configuration repo runs with: c with every matching
+// override applied in order. c itself is returned when none match.
+func (c *Config) ForRepo(repo string) *Config {
+	cfg := c
+	for _, o := range c.Overrides {
+		if !o.matches(repo) {
+			continue
+		}
+		if cfg == c {
+			copied := *c
+			cfg = &copied
+		}
+
+		if o.DryRun != "" {
+			cfg.DryRun = o.DryRun
+		}
+		if o.Onboarding != nil {
+			cfg.Onboarding = *o.Onboarding
+		}
+		if o.RequireConfig != "" {
+			cfg.RequireConfig = o.RequireConfig
+		}
+	}
+	return cfg
+}
+
+func (o RepoOverride) validate() error {
+	for _, pattern := range o.Repos {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid override pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (o RepoOverride) matches(repo string) bool {
+	for _, pattern := range o.Repos {
+		if ok, _ := path.Match(pattern, repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RepoURL returns the web URL of repo on the configured platform, or "" when
+// it cannot be derived from the endpoint.
+func (c *Config) RepoURL(repo string) string {
+	base := strings.TrimSuffix(c.Endpoint, "/")
+
+	switch c.Platform {
+	case "github":
+		if base == "" || base == "https://api.github.com" {
+			return "https://github.com/" + repo
+		}
+		// GitHub Enterprise Server serves its API under /api/v3
+		return strings.TrimSuffix(base, "/api/v3") + "/" + repo
+	case "gitlab":
+		if base == "" {
+			return "https://gitlab.com/" + repo
+		}
+		return strings.TrimSuffix(base, "/api/v4") + "/" + repo
+	case "gitea":
+		if base == "" {
+			return ""
+		}
+		return strings.TrimSuffix(base, "/api/v1") + "/" + repo
+	case "bitbucket-server":
+		project, slug, ok := strings.Cut(repo, "/")
+		if base == "" || !ok {
+			return ""
+		}
+		return strings.TrimSuffix(base, "/rest/api/1.0") + "/projects/" + project + "/repos/" + slug
+	case "azure":
+		project, name, ok := strings.Cut(repo, "/")
+		if base == "" || !ok {
+			return ""
+		}
+		return base + "/" + url.PathEscape(project) + "/_git/" + url.PathEscape(name)
+	default:
+		return ""
+	}
+}
+
+// parseLogs reports whether Renovate's stdout carries the debug messages
+// updates are parsed from.
+func (c *Config) parseLogs() bool {
+	return c.LogLevel == "" || c.LogLevel == "debug" || c.LogLevel == "trace"
+}
+
+func (c *Config) setupGitHubApp() error {
+	c.tokenSource = nil
+	if !c.GitHubApp.enabled() {
+		return nil
+	}
+	if c.Platform != "github" {
+		return fmt.Errorf("github_app requires the github platform, got %q", c.Platform)
+	}
+
+	key := []byte(c.GitHubApp.PrivateKey)
+	if c.GitHubApp.PrivateKeyPath != "" {
+		var err error
+		key, err = os.ReadFile(c.GitHubApp.PrivateKeyPath)
+		if err != nil {
+			return fmt.Errorf("failed to read GitHub App private key: %w", err)
+		}
+	}
+
+	ts, err := githubapp.NewTokenSource(c.Endpoint, c.GitHubApp.AppID, c.GitHubApp.InstallationID, key)
+	if err != nil {
+		return fmt.Errorf("invalid github_app config: %w", err)
+	}
+	c.tokenSource = ts
+	return nil
+}
+
+// TokenSource returns the source of the platform token: GitHub App
+// installation tokens when configured, the static token otherwise.
+func (c *Config) TokenSource() oauth2.TokenSource {
+	if c.tokenSource != nil {
+		return c.tokenSource
+	}
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token})
+}
+
+// ForSource returns a copy of c that discovers and runs Renovate with the
+// platform settings and filters of src.
+func (c *Config) ForSource(src DiscoverySource) (*Config, error) {
+	cfg := *c
+	if src.Platform != "" {
+		cfg.Platform = src.Platform
+	}
+	if src.Token != "" {
+		// A static token replaces the top-level GitHub App
+		cfg.Token = src.Token
+		cfg.GitHubApp = GitHubAppConfig{}
+		cfg.tokenSource = nil
+	}
+	if src.GitHubApp.enabled() {
+		cfg.GitHubApp = src.GitHubApp
+	}
+	if src.Endpoint != "" {
+		cfg.Endpoint = src.Endpoint
+	}
+	cfg.Discovery = DiscoveryConfig{
+		Enabled:          true,
+		DiscoveryFilters: src.DiscoveryFilters,
+		Cache:            c.Discovery.Cache,
+	}
+
+	// Reuse the top-level installation tokens unless the app or host changed
+	if cfg.GitHubApp.enabled() && (src.GitHubApp.enabled() || src.Endpoint != "" || src.Platform != "") {
+		if err := cfg.setupGitHubApp(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &cfg, nil
 }
 
-func (c *Config) ToEnv() []string {
+// ToEnv returns the environment of a Renovate run. A GitHub App installation
+// token is minted here so every run gets the full hour of validity.
+func (c *Config) ToEnv() ([]string, error) {
 	envs := os.Environ()
 
 	if c.Platform != "" {
 		envs = append(envs, fmt.Sprintf("RENOVATE_PLATFORM=%s", c.Platform))
 	}
-	if c.Token != "" {
-		envs = append(envs, fmt.Sprintf("RENOVATE_TOKEN=%s", c.Token))
+
+	var token *oauth2.Token
+	var err error
+	if c.tokenSource != nil {
+		token, err = c.tokenSource.NewToken()
+	} else {
+		token, err = c.TokenSource().Token()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get platform token: %w", err)
+	}
+	if token.AccessToken != "" {
+		envs = append(envs, fmt.Sprintf("RENOVATE_TOKEN=%s", token.AccessToken))
 	}
 	if c.Endpoint != "" {
 		envs = append(envs, fmt.Sprintf("RENOVATE_ENDPOINT=%s", c.Endpoint))
 	}
 
-	// Enforced options
-	envs = append(envs, "LOG_LEVEL=debug")
-	envs = append(envs, "RENOVATE_DRY_RUN=true")
-	envs = append(envs, "RENOVATE_ONBOARDING=false")
-	envs = append(envs, "RENOVATE_REQUIRE_CONFIG=optional")
+	logLevel := c.LogLevel
+	if logLevel == "" {
+		logLevel = "debug"
+	}
+	envs = append(envs, fmt.Sprintf("LOG_LEVEL=%s", logLevel))
+
+	// Without a dry run mode, Renovate creates branches and pull requests
+	switch c.DryRun {
+	case "":
+		envs = append(envs, fmt.Sprintf("RENOVATE_DRY_RUN=%s", DryRunFull))
+	case DryRunOff:
+	default:
+		envs = append(envs, fmt.Sprintf("RENOVATE_DRY_RUN=%s", c.DryRun))
+	}
 
+	envs = append(envs, fmt.Sprintf("RENOVATE_ONBOARDING=%t", c.Onboarding))
+
+	requireConfig := c.RequireConfig
+	if requireConfig == "" {
+		requireConfig = "optional"
+	}
+	envs = append(envs, fmt.Sprintf("RENOVATE_REQUIRE_CONFIG=%s", requireConfig))
+
+	// The parser needs JSON logs
 	envs = append(envs, "LOG_FORMAT=json")
 
 	for k, v := range c.ExtraEnv {
 		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	return envs
+	return envs, nil
+}
+
+// RunError is returned by Run when Renovate fails.
+type RunError struct {
+	// ExitCode is -1 when Renovate was killed or could not be started
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *RunError) Error() string {
+	return fmt.Sprintf("failed to run renovate: %v\nstderr: %s", e.Err, e.Stderr)
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// killGracePeriod is how long Renovate may take to exit after being
+// terminated when its context is cancelled, before it is killed.
+const killGracePeriod = 10 * time.Second
+
+// maxStderrSize bounds the stderr kept for error messages.
+const maxStderrSize = 64 << 10
+
+// ValidateRepo reports an error if repo is not an owner/name repository path.
+// Names are passed to Renovate after "--", so only what could still be taken
+// for an option or escape the path is rejected: segments that are empty,
+// "." or "..", or start with "-", and control characters.
+func ValidateRepo(repo string) error {
+	segments := strings.Split(repo, "/")
+	if len(segments) < 2 {
+		return fmt.Errorf("invalid repository %q: expected owner/name", repo)
+	}
+	for _, seg := range segments {
+		if seg == "" || seg == "." || seg == ".." || strings.HasPrefix(seg, "-") {
+			return fmt.Errorf("invalid repository %q: expected owner/name", repo)
+		}
+	}
+	if strings.ContainsFunc(repo, unicode.IsControl) {
+		return fmt.Errorf("invalid repository %q: contains control characters", repo)
+	}
+	return nil
+}
+
+// Run runs Renovate for repo and streams its JSON log to out as it is
+// produced. The log is written even when Renovate fails so that it can still
+// be inspected.
+func (c *Config) Run(ctx context.Context, repo string, out io.Writer) error {
+	if err := ValidateRepo(repo); err != nil {
+		return err
+	}
+
+	env, err := c.ToEnv()
+	if err != nil {
+		return err
+	}
+
+	// Updates are only logged at debug level. With a higher log level, a
+	// debug log file is kept aside and streamed to out once Renovate exits.
+	var logFile string
+	if !c.parseLogs() {
+		f, err := os.CreateTemp("", "renovate-*.log")
+		if err != nil {
+			return fmt.Errorf("failed to create log file: %w", err)
+		}
+		f.Close()
+		logFile = f.Name()
+		defer os.Remove(logFile)
+
+		env = append(env, fmt.Sprintf("LOG_FILE=%s", logFile), "LOG_FILE_LEVEL=debug")
+	}
+
+	cmd := exec.CommandContext(ctx, c.Command, "--", repo)
+	cmd.Env = env
+	setCancel(cmd)
+	cmd.WaitDelay = killGracePeriod
+
+	stderr := &headBuffer{max: maxStderrSize}
+	cmd.Stdout = out
+	if logFile != "" {
+		cmd.Stdout = io.Discard
+	}
+	cmd.Stderr = stderr
+
+	runErr := cmd.Run()
+
+	if logFile != "" {
+		err := copyFile(out, logFile)
+		if err != nil && runErr == nil {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+	}
+
+	if runErr != nil {
+		exitCode := -1
+		if cmd.ProcessState != nil {
+			exitCode = cmd.ProcessState.ExitCode()
+		}
+		return &RunError{ExitCode: exitCode, Stderr: stderr.String(), Err: runErr}
+	}
+
+	return nil
 }
 
-func (c *Config) Run(ctx context.Context, repo string) ([]byte, error) {
-	cmd := exec.CommandContext(ctx, c.Command, repo)
-	cmd.Env = c.ToEnv()
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// headBuffer keeps the first max bytes written to it and discards the rest.
+type headBuffer struct {
+	buf bytes.Buffer
+	max int
+}
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to run renovate: %w\nstdout: %s\nstderr: %s", err, stdout.String(), stderr.String())
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
 	}
+	return len(p), nil
+}
 
-	return stdout.Bytes(), nil
+func (b *headBuffer) String() string {
+	return b.buf.String()
 }
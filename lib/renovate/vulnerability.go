@@ -0,0 +1,188 @@
+package renovate
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var vulnerabilityIDPattern = regexp.MustCompile(`\b(GHSA(?:-[23456789cfghjmpqrvwx]{4}){3}|CVE-\d{4}-\d{4,})\b`)
+
+var severityRank = map[string]int{
+	"LOW":      1,
+	"MODERATE": 2,
+	"MEDIUM":   2,
+	"HIGH":     3,
+	"CRITICAL": 4,
+}
+
+// vulnerabilityAlert is a GitHub Dependabot alert as logged by Renovate.
+type vulnerabilityAlert struct {
+	SecurityAdvisory struct {
+		GhsaID      string `json:"ghsa_id"`
+		CveID       string `json:"cve_id"`
+		Severity    string `json:"severity"`
+		Identifiers []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"identifiers"`
+	} `json:"security_advisory"`
+	SecurityVulnerability struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		FirstPatchedVersion struct {
+			Identifier string `json:"identifier"`
+		} `json:"first_patched_version"`
+	} `json:"security_vulnerability"`
+}
+
+func (a vulnerabilityAlert) ids() []string {
+	var ids []string
+	if a.SecurityAdvisory.GhsaID != "" {
+		ids = append(ids, a.SecurityAdvisory.GhsaID)
+	}
+	if a.SecurityAdvisory.CveID != "" {
+		ids = append(ids, a.SecurityAdvisory.CveID)
+	}
+	for _, id := range a.SecurityAdvisory.Identifiers {
+		ids = append(ids, id.Value)
+	}
+	return mergeIDs(nil, ids)
+}
+
+// ecosystems maps GitHub advisory ecosystems to the Renovate datasources and
+// managers of the same package registry.
+var ecosystems = map[string][]string{
+	"NPM":      {"npm", "yarn", "pnpm"},
+	"PIP":      {"pypi", "pip_requirements", "pip_setup", "pipenv", "poetry", "pep621", "setup-cfg"},
+	"GO":       {"go", "gomod"},
+	"MAVEN":    {"maven", "gradle", "sbt"},
+	"NUGET":    {"nuget"},
+	"RUBYGEMS": {"rubygems", "bundler", "gemspec"},
+	"COMPOSER": {"packagist", "composer"},
+	"RUST":     {"crate", "cargo"},
+	"PUB":      {"dart", "pub"},
+	"ERLANG":   {"hex", "mix"},
+	"ACTIONS":  {"github-actions", "github-tags", "github-releases"},
+	"SWIFT":    {"swift", "git-tags", "github-tags"},
+}
+
+// sameEcosystem reports whether an alert for ecosystem can concern u. An
+// update whose datasource and manager are both unknown never matches.
+func sameEcosystem(ecosystem string, u UpdateInfo) bool {
+	if ecosystem == "" {
+		return true
+	}
+
+	names := ecosystems[strings.ToUpper(ecosystem)]
+	for _, name := range []string{u.Datasource, u.Manager} {
+		if name != "" && (slices.Contains(names, name) || strings.EqualFold(name, ecosystem)) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyVulnerabilityAlerts flags u as a security fix when an alert for the
+// same package and ecosystem is fixed by the new version, and adds the
+// details of the alerts it fixes.
+func applyVulnerabilityAlerts(u UpdateInfo, alerts []vulnerabilityAlert) UpdateInfo {
+	for _, a := range alerts {
+		pkg := a.SecurityVulnerability.Package
+		if !strings.EqualFold(pkg.Name, u.DepName) || !sameEcosystem(pkg.Ecosystem, u) {
+			continue
+		}
+
+		patched := a.SecurityVulnerability.FirstPatchedVersion.Identifier
+		if patched == "" || !versionAtLeast(u.NewVersion, patched) {
+			continue
+		}
+
+		u.IsVulnerabilityAlert = true
+		u.Vulnerabilities = mergeIDs(u.Vulnerabilities, a.ids())
+		if severity := normalizeSeverity(a.SecurityAdvisory.Severity); severityRank[severity] > severityRank[u.VulnerabilitySeverity] {
+			u.VulnerabilitySeverity = severity
+		}
+	}
+	return u
+}
+
+func extractVulnerabilityIDs(texts ...string) []string {
+	var ids []string
+	for _, text := range texts {
+		ids = append(ids, vulnerabilityIDPattern.FindAllString(text, -1)...)
+	}
+	return mergeIDs(nil, ids)
+}
+
+func normalizeSeverity(severity string) string {
+	return strings.ToUpper(strings.TrimSpace(severity))
+}
+
+// mergeIDs returns the sorted union of both ID lists.
+func mergeIDs(a, b []string) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range append(append([]string{}, a...), b...) {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// versionAtLeast compares dotted numeric versions such as "v1.2.3" and
+// reports false when either version cannot be compared.
+func versionAtLeast(version, min string) bool {
+	v, ok := numericVersion(version)
+	if !ok {
+		return false
+	}
+	m, ok := numericVersion(min)
+	if !ok {
+		return false
+	}
+
+	for i := 0; i < max(len(v), len(m)); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(m) {
+			b = m[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+func numericVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	// Ignore pre-release and build metadata
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, false
+	}
+
+	var parts []int
+	for _, p := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
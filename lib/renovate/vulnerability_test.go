@@ -0,0 +1,71 @@
+package renovate
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestApplyVulnerabilityAlerts(t *testing.T) {
+	var alerts []vulnerabilityAlert
+	err := json.Unmarshal([]byte(`[
+		{"security_advisory":{"ghsa_id":"GHSA-aaaa-bbbb-cccc","severity":"high"},"security_vulnerability":{"package":{"ecosystem":"npm","name":"lodash"},"first_patched_version":{"identifier":"4.17.21"}}},
+		{"security_advisory":{"ghsa_id":"GHSA-dddd-ffff-gggg","severity":"critical"},"security_vulnerability":{"package":{"ecosystem":"npm","name":"lodash"},"first_patched_version":{"identifier":"5.0.0"}}}
+	]`), &alerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		update   UpdateInfo
+		wantIDs  []string
+		severity string
+	}{
+		{
+			name:     "fixed alert only",
+			update:   UpdateInfo{DepName: "lodash", NewVersion: "4.17.21", Datasource: "npm"},
+			wantIDs:  []string{"GHSA-aaaa-bbbb-cccc"},
+			severity: "HIGH",
+		},
+		{
+			name:     "every fixed alert",
+			update:   UpdateInfo{DepName: "lodash", NewVersion: "5.1.0", Manager: "npm"},
+			wantIDs:  []string{"GHSA-aaaa-bbbb-cccc", "GHSA-dddd-ffff-gggg"},
+			severity: "CRITICAL",
+		},
+		{
+			name:   "not fixed by the new version",
+			update: UpdateInfo{DepName: "lodash", NewVersion: "4.17.20", Datasource: "npm"},
+		},
+		{
+			name:   "same name in another ecosystem",
+			update: UpdateInfo{DepName: "lodash", NewVersion: "5.1.0", Datasource: "pypi", Manager: "pip_requirements"},
+		},
+		{
+			name:   "unknown ecosystem",
+			update: UpdateInfo{DepName: "lodash", NewVersion: "5.1.0"},
+		},
+		{
+			name:     "already flagged by Renovate",
+			update:   UpdateInfo{DepName: "lodash", NewVersion: "4.17.21", Datasource: "npm", IsVulnerabilityAlert: true},
+			wantIDs:  []string{"GHSA-aaaa-bbbb-cccc"},
+			severity: "HIGH",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyVulnerabilityAlerts(tt.update, alerts)
+			if !slices.Equal(got.Vulnerabilities, tt.wantIDs) {
+				t.Errorf("Vulnerabilities = %v, want %v", got.Vulnerabilities, tt.wantIDs)
+			}
+			if got.VulnerabilitySeverity != tt.severity {
+				t.Errorf("VulnerabilitySeverity = %q, want %q", got.VulnerabilitySeverity, tt.severity)
+			}
+			if want := tt.update.IsVulnerabilityAlert || len(tt.wantIDs) > 0; got.IsVulnerabilityAlert != want {
+				t.Errorf("IsVulnerabilityAlert = %t, want %t", got.IsVulnerabilityAlert, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+const defaultLogDir = "renovates-logs"
+
+// runMarker marks the run directories of a log store. Only marked directories
+// are pruned, so the store can share its directory with other files.
+const runMarker = ".renovates-run"
+
+// logStore persists the raw Renovate log of every repository under
+// dir/<run ID>/<repo>.log, optionally gzip-compressed.
+type logStore struct {
+	dir     string
+	gzip    bool
+	maxRuns int
+	maxAge  time.Duration
+}
+
+func newLogStore(cfg renovate.LogsConfig) (*logStore, error) {
+	dir := cfg.Path
+	if dir == "" {
+		dir = defaultLogDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	return &logStore{
+		dir:     dir,
+		gzip:    cfg.Gzip,
+		maxRuns: cfg.MaxRuns,
+		maxAge:  time.Duration(cfg.MaxAge),
+	}, nil
+}
+
+// newRunID returns a run ID that sorts chronologically.
+func newRunID() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+// path returns the log file of repo in the given run.
+func (s *logStore) path(runID, repo string) string {
+	segments := strings.Split(repo, "/")
+	for i, seg := range segments {
+		// Repository names come from user input; never leave the run directory
+		if seg == "" || seg == "." || seg == ".." {
+			segments[i] = "_"
+		}
+	}
+
+	name := filepath.Join(segments...) + ".log"
+	if s.gzip {
+		name += ".gz"
+	}
+	return filepath.Join(s.dir, runID, name)
+}
+
+// create opens the log file of repo, replacing the log of a previous attempt.
+func (s *logStore) create(runID, repo string) (*logFile, error) {
+	path := s.path(runID, repo)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.dir, runID, runMarker), nil, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to mark run directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file: %w", err)
+	}
+
+	lf := &logFile{path: path, f: f}
+	if s.gzip {
+		lf.gz = gzip.NewWriter(f)
+	}
+	return lf, nil
+}
+
+// prune removes the runs beyond the retention limits, oldest first. keep and
+// directories not created by the store are never removed.
+func (s *logStore) prune(keep string) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.Printf("failed to list logs: %v", err)
+		return
+	}
+
+	type run struct {
+		name    string
+		modTime time.Time
+	}
+	var runs []run
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == keep {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.dir, e.Name(), runMarker)); err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run{name: e.Name(), modTime: info.ModTime()})
+	}
+
+	// Newest first; the current run counts towards max_runs
+	sort.Slice(runs, func(i, j int) bool { return runs[i].modTime.After(runs[j].modTime) })
+	for i, r := range runs {
+		expired := s.maxAge > 0 && time.Since(r.modTime) > s.maxAge
+		excess := s.maxRuns > 0 && i+1 >= s.maxRuns
+		if !expired && !excess {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, r.name)); err != nil {
+			log.Printf("failed to remove logs of run %s: %v", r.name, err)
+		}
+	}
+}
+
+// logFile is the raw log of one repository. Write errors are logged once and
+// never fail the Renovate run it is attached to.
+type logFile struct {
+	path string
+	f    *os.File
+	gz   *gzip.Writer
+	err  error
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	if l.err != nil {
+		return len(p), nil
+	}
+
+	var err error
+	if l.gz != nil {
+		_, err = l.gz.Write(p)
+	} else {
+		_, err = l.f.Write(p)
+	}
+	if err != nil {
+		l.err = err
+		log.Printf("failed to write log %s: %v", l.path, err)
+	}
+	return len(p), nil
+}
+
+func (l *logFile) Close() error {
+	if l.gz != nil {
+		if err := l.gz.Close(); err != nil && l.err == nil {
+			l.err = err
+		}
+	}
+	if err := l.f.Close(); err != nil && l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
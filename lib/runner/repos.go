@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+// ReadTargets reads a repository list: one repository per line, optionally
+// followed by key=value overrides of the platform and run settings, which take
+// precedence over the configured repository overrides. Blank lines and
+// comments starting with # are ignored. Values may reference environment
+// variables as $NAME.
+//
+//	# Service catalog export
+//	my-org/api
+//	infra/deploy platform=gitlab endpoint=https://gitlab.example.com token=$GITLAB_TOKEN
+//	my-org/web env.RENOVATE_BASE_BRANCHES=develop
+//	my-org/sandbox dry_run=off
+func (r *Runner) ReadTargets(rd io.Reader) ([]Target, error) {
+	var targets []Target
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(rd)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i == 0 || (i > 0 && (line[i-1] == ' ' || line[i-1] == '\t')) {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		t, err := r.parseTarget(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if seen[t.Repo] {
+			continue
+		}
+		seen[t.Repo] = true
+		targets = append(targets, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read repository list: %w", err)
+	}
+
+	return targets, nil
+}
+
+func (r *Runner) parseTarget(fields []string) (Target, error) {
+	if err := renovate.ValidateRepo(fields[0]); err != nil {
+		return Target{}, err
+	}
+
+	base := r.cfg.ForRepo(fields[0])
+	t := Target{Repo: fields[0], Config: base}
+	if len(fields) == 1 {
+		return t, nil
+	}
+
+	var src renovate.DiscoverySource
+	var run renovate.RepoOverride
+	env := make(map[string]string)
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Target{}, fmt.Errorf("invalid override %q: expected key=value", field)
+		}
+		value = os.ExpandEnv(value)
+
+		switch {
+		case key == "platform":
+			src.Platform = value
+		case key == "endpoint":
+			src.Endpoint = value
+		case key == "token":
+			src.Token = value
+		case key == "dry_run":
+			if err := run.DryRun.UnmarshalText([]byte(value)); err != nil {
+				return Target{}, err
+			}
+		case key == "onboarding":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Target{}, fmt.Errorf("invalid onboarding %q: %w", value, err)
+			}
+			run.Onboarding = &b
+		case key == "require_config":
+			run.RequireConfig = value
+		case strings.HasPrefix(key, "env.") && len(key) > len("env."):
+			env[strings.TrimPrefix(key, "env.")] = value
+		default:
+			return Target{}, fmt.Errorf("unknown override %q", key)
+		}
+	}
+
+	cfg, err := base.ForSource(src)
+	if err != nil {
+		return Target{}, err
+	}
+	if run.DryRun != "" {
+		cfg.DryRun = run.DryRun
+	}
+	if run.Onboarding != nil {
+		cfg.Onboarding = *run.Onboarding
+	}
+	if run.RequireConfig != "" {
+		cfg.RequireConfig = run.RequireConfig
+	}
+	if len(env) > 0 {
+		cfg.ExtraEnv = maps.Clone(base.ExtraEnv)
+		if cfg.ExtraEnv == nil {
+			cfg.ExtraEnv = make(map[string]string)
+		}
+		maps.Copy(cfg.ExtraEnv, env)
+	}
+
+	t.Config = cfg
+	return t, nil
+}
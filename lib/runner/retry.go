@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+const (
+	defaultBackoff    = 30 * time.Second
+	defaultMaxBackoff = 10 * time.Minute
+)
+
+// transientMarkers appear in Renovate's errors when a run failed because of
+// the network or a rate limit rather than because of the repository.
+var transientMarkers = []string{
+	"external-host-error",
+	"rate-limit-exceeded",
+	"temporary-error",
+	"ECONNRESET",
+	"ECONNREFUSED",
+	"ETIMEDOUT",
+	"EAI_AGAIN",
+	"socket hang up",
+}
+
+// attempt is the outcome of one Renovate run.
+type attempt struct {
+	result   renovate.Result
+	err      error
+	timedOut bool
+	logPath  string
+}
+
+// runWithRetry runs Renovate for t until it succeeds, fails permanently or
+// runs out of attempts. It returns ctx's error when interrupted.
+func (r *Runner) runWithRetry(ctx context.Context, runID string, t Target) (attempt, error) {
+	retry := t.Config.Retry
+	attempts := max(retry.Attempts, 1)
+
+	for i := 1; ; i++ {
+		a := r.runAttempt(ctx, runID, t)
+		if ctx.Err() != nil {
+			return a, ctx.Err()
+		}
+		if a.err == nil || i >= attempts || !a.transient(retry.ExitCodes) {
+			return a, nil
+		}
+
+		delay := backoff(retry, i)
+		log.Printf("renovate for %s failed (attempt %d/%d), retrying in %s: %v", t.Repo, i, attempts, delay, a.err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return a, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// runAttempt runs Renovate once, saving its raw log when logs are kept. A
+// retry replaces the log of the previous attempt.
+func (r *Runner) runAttempt(ctx context.Context, runID string, t Target) attempt {
+	runCtx := ctx
+	if timeout := time.Duration(t.Config.Timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	parser := renovate.NewParser()
+	var out io.Writer = parser
+
+	var logPath string
+	if r.logs != nil {
+		lf, err := r.logs.create(runID, r.targetKey(t))
+		if err != nil {
+			log.Printf("failed to save renovate log for %s: %v", t.Repo, err)
+		} else {
+			defer func() {
+				if err := lf.Close(); err != nil {
+					log.Printf("failed to save renovate log for %s: %v", t.Repo, err)
+				}
+			}()
+			out = io.MultiWriter(parser, lf)
+			logPath = lf.path
+		}
+	}
+
+	err := t.Config.Run(runCtx, t.Repo, out)
+	return attempt{
+		result:   parser.Result(),
+		err:      err,
+		timedOut: err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded),
+		logPath:  logPath,
+	}
+}
+
+// transient reports whether a failed attempt is worth retrying.
+func (a attempt) transient(exitCodes []int) bool {
+	if a.timedOut {
+		return true
+	}
+
+	var runErr *renovate.RunError
+	if errors.As(a.err, &runErr) {
+		if slices.Contains(exitCodes, runErr.ExitCode) || hasTransientMarker(runErr.Stderr) {
+			return true
+		}
+	}
+
+	for _, p := range a.result.Problems {
+		if p.IsError() && hasTransientMarker(p.Message+" "+p.Error) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasTransientMarker(s string) bool {
+	for _, marker := range transientMarkers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay after the given failed attempt.
+func backoff(retry renovate.RetryConfig, attempt int) time.Duration {
+	delay := time.Duration(retry.Backoff)
+	if delay <= 0 {
+		delay = defaultBackoff
+	}
+	maxDelay := time.Duration(retry.MaxBackoff)
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxBackoff
+	}
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,433 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/discovery"
+	"github.com/snowmerak/renovates/lib/notifier"
+	"github.com/snowmerak/renovates/lib/renovate"
+	"github.com/snowmerak/renovates/lib/state"
+)
+
+// Runner runs Renovate against a set of repositories and dispatches the
+// results to the configured notifiers.
+type Runner struct {
+	cfg             *renovate.Config
+	notifiers       []notifier.Notifier
+	digestNotifiers []notifier.DigestNotifier
+	store           *state.Store
+	sources         []source
+	logs            *logStore
+
+	mu     sync.Mutex
+	latest map[string]Result
+}
+
+// source is a discoverer and the configuration its repositories run with.
+type source struct {
+	name       string
+	cfg        *renovate.Config
+	discoverer discovery.Discoverer
+}
+
+// Result holds every pending update found by the last successful run of a
+// repository, regardless of which updates were notified.
+type Result struct {
+	Repo      string                `json:"repo"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+	Updates   []renovate.UpdateInfo `json:"updates"`
+	Problems  []renovate.Problem    `json:"problems,omitempty"`
+}
+
+// Renovate's stderr can be long; problems only keep the beginning.
+const maxErrorLength = 1000
+
+// notifyTimeout bounds each notifier call, so a hung SMTP server or webhook
+// endpoint cannot block a run or its shutdown.
+const notifyTimeout = 2 * time.Minute
+
+func New(cfg *renovate.Config) (*Runner, error) {
+	r, err := NewNotifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Discoverers are created up front so invalid filters fail at startup
+	if cfg.Discovery.Enabled {
+		if len(cfg.Discovery.Sources) == 0 {
+			d, err := discovery.NewDiscoverer(cfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create discoverer: %w", err)
+			}
+			r.sources = append(r.sources, source{cfg: cfg, discoverer: d})
+		}
+		for i, src := range cfg.Discovery.Sources {
+			name := src.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+
+			srcCfg, err := cfg.ForSource(src)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source %s: %w", name, err)
+			}
+			d, err := discovery.NewDiscoverer(srcCfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create discoverer for source %s: %w", name, err)
+			}
+			r.sources = append(r.sources, source{name: name, cfg: srcCfg, discoverer: d})
+		}
+	}
+
+	if cfg.Logs.Enabled {
+		logs, err := newLogStore(cfg.Logs)
+		if err != nil {
+			return nil, err
+		}
+		r.logs = logs
+	}
+
+	return r, nil
+}
+
+// NewNotifier returns a Runner that only sends results passed to Notify. It
+// does not create discoverers or the logs directory, so it has no side
+// effects besides the notifications and the state store.
+func NewNotifier(cfg *renovate.Config) (*Runner, error) {
+	r := &Runner{
+		cfg:    cfg,
+		latest: make(map[string]Result),
+	}
+
+	for _, nc := range cfg.Notifiers {
+		n, err := notifier.New(nc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create notifier: %w", err)
+		}
+
+		if !nc.Digest {
+			r.notifiers = append(r.notifiers, n)
+			continue
+		}
+
+		dn, ok := n.(notifier.DigestNotifier)
+		if !ok {
+			return nil, fmt.Errorf("notifier %s does not support digest mode", nc.Type)
+		}
+		r.digestNotifiers = append(r.digestNotifiers, dn)
+	}
+
+	if cfg.State.Enabled {
+		path := cfg.State.Path
+		if path == "" {
+			path = "renovates-state.json"
+		}
+		store, err := state.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open state store: %w", err)
+		}
+		r.store = store
+	}
+
+	return r, nil
+}
+
+// Target is a repository and the configuration Renovate runs it with.
+type Target struct {
+	Repo   string
+	Config *renovate.Config
+}
+
+func (t Target) String() string {
+	return t.Repo
+}
+
+// key identifies a repository across sources, for the state store, the
+// latest results and the log files. Repositories on the top-level platform
+// keep their bare name, as recorded before sources existed; others are
+// prefixed with "platform@endpoint", which no repository owner can be named.
+func (r *Runner) key(platform, endpoint, repo string) string {
+	if platform == r.cfg.Platform && endpoint == r.cfg.Endpoint {
+		return repo
+	}
+
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+	return platform + "@" + strings.Trim(endpoint, "/") + "/" + repo
+}
+
+func (r *Runner) targetKey(t Target) string {
+	return r.key(t.Config.Platform, t.Config.Endpoint, t.Repo)
+}
+
+// Targets returns targets for repos using the top-level platform settings
+// and the matching repository overrides.
+func (r *Runner) Targets(repos []string) []Target {
+	targets := make([]Target, 0, len(repos))
+	for _, repo := range repos {
+		targets = append(targets, Target{Repo: repo, Config: r.cfg.ForRepo(repo)})
+	}
+	return targets
+}
+
+// Discover lists the repositories matching the discovery settings, from
+// every configured source.
+func (r *Runner) Discover(ctx context.Context) ([]Target, error) {
+	if len(r.sources) == 0 {
+		return nil, fmt.Errorf("discovery is not enabled")
+	}
+
+	var targets []Target
+	seen := make(map[string]bool)
+	for _, src := range r.sources {
+		repos, err := src.discoverer.ListRepositories(ctx)
+		if err != nil {
+			if src.name != "" {
+				return nil, fmt.Errorf("failed to discover repositories from source %s: %w", src.name, err)
+			}
+			return nil, fmt.Errorf("failed to discover repositories: %w", err)
+		}
+
+		// Overlapping sources on the same host would scan a repository twice
+		for _, repo := range repos {
+			key := r.key(src.cfg.Platform, src.cfg.Endpoint, repo)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			targets = append(targets, Target{Repo: repo, Config: src.cfg.ForRepo(repo)})
+		}
+	}
+
+	return targets, nil
+}
+
+// Run runs Renovate for every target and returns their reports sorted by
+// repository. Repositories that failed carry the failure in their problems.
+//
+// When ctx is cancelled, running Renovate processes are terminated and no
+// further repositories are started. Repositories that completed before are
+// still reported and notified.
+func (r *Runner) Run(ctx context.Context, targets []Target) []notifier.Report {
+	return r.RunWithID(ctx, newRunID(), targets)
+}
+
+// RunWithID is Run with the run ID that keys the saved logs.
+func (r *Runner) RunWithID(ctx context.Context, runID string, targets []Target) []notifier.Report {
+	if r.logs != nil {
+		log.Printf("saving renovate logs of run %s to %s", runID, filepath.Join(r.logs.dir, runID))
+	}
+
+	concurrency := r.cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	var mu sync.Mutex
+	var reports []notifier.Report
+
+	// Notifications about completed repositories must go out even when the
+	// run is interrupted; each one is still bounded by notifyTimeout.
+	notifyCtx := context.WithoutCancel(ctx)
+
+	for _, t := range targets {
+		select {
+		case sem <- struct{}{}: // Acquire semaphore
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(t Target) {
+			defer wg.Done()
+			defer func() { <-sem }() // Release semaphore
+
+			report, ok := r.runRepo(ctx, runID, t)
+			if !ok {
+				return
+			}
+
+			mu.Lock()
+			reports = append(reports, report)
+			mu.Unlock()
+
+			r.notify(notifyCtx, report)
+		}(t)
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		log.Printf("run interrupted, %d of %d repositories completed", len(reports), len(targets))
+	}
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Repo < reports[j].Repo })
+	r.notifyDigest(notifyCtx, reports)
+
+	if r.logs != nil {
+		r.logs.prune(runID)
+	}
+
+	return reports
+}
+
+// runRepo runs Renovate for t and builds its report. It returns false when the
+// run was interrupted by ctx.
+func (r *Runner) runRepo(ctx context.Context, runID string, t Target) (notifier.Report, bool) {
+	repo := t.Repo
+	fmt.Printf("Running renovate for %s...\n", repo)
+
+	a, err := r.runWithRetry(ctx, runID, t)
+	if err != nil {
+		log.Printf("renovate for %s was interrupted: %v", repo, err)
+		return notifier.Report{}, false
+	}
+
+	report := notifier.Report{
+		Repo:     repo,
+		Updates:  a.result.Updates,
+		Problems: a.result.Problems,
+		URL:      t.Config.RepoURL(repo),
+		LogPath:  a.logPath,
+	}
+
+	if runErr := a.err; runErr != nil {
+		log.Printf("failed to run renovate for %s: %v", repo, runErr)
+		message := "Renovate exited with an error"
+		if a.timedOut {
+			message = fmt.Sprintf("Renovate timed out after %s", time.Duration(t.Config.Timeout))
+		}
+		report.Problems = append(report.Problems, renovate.Problem{
+			Level:   "fatal",
+			Message: message,
+			Error:   truncate(runErr.Error(), maxErrorLength),
+		})
+
+		// An incomplete scan must not overwrite the recorded state, and
+		// missing updates cannot be reported as resolved.
+		if r.store != nil {
+			changes := r.store.Diff(repo, report.Updates, report.Problems)
+			if r.cfg.State.OnlyNew {
+				report.Updates = changes.Added
+			}
+			if r.cfg.State.OnlyNewProblems {
+				report.Problems = changes.NewProblems
+			}
+		}
+		return report, true
+	}
+
+	return r.record(r.targetKey(t), report), true
+}
+
+// record remembers the updates of a complete scan under key and applies
+// change tracking to report.
+func (r *Runner) record(key string, report notifier.Report) notifier.Report {
+	repo := report.Repo
+
+	r.mu.Lock()
+	r.latest[key] = Result{Repo: repo, UpdatedAt: time.Now(), Updates: report.Updates, Problems: report.Problems}
+	r.mu.Unlock()
+
+	if r.store != nil {
+		changes, err := r.store.Record(key, report.Updates, report.Problems)
+		if err != nil {
+			log.Printf("failed to record state for %s: %v", repo, err)
+		}
+		if err == nil && r.cfg.State.OnlyNew {
+			report.Updates = changes.Added
+		}
+		if err == nil && r.cfg.State.OnlyNewProblems {
+			report.Problems = changes.NewProblems
+		}
+		if r.cfg.State.IncludeResolved {
+			report.Resolved = changes.Resolved
+		}
+	}
+
+	return report
+}
+
+// Notify sends a result parsed outside of a run, such as a log of a Renovate
+// run in CI, to the notifiers as if Renovate had just scanned repo. Change
+// tracking and digest notifiers apply as for a run of one repository.
+func (r *Runner) Notify(ctx context.Context, repo string, result renovate.Result) notifier.Report {
+	report := r.record(repo, notifier.Report{
+		Repo:     repo,
+		Updates:  result.Updates,
+		Problems: result.Problems,
+		URL:      r.cfg.RepoURL(repo),
+	})
+
+	r.notify(ctx, report)
+	r.notifyDigest(ctx, []notifier.Report{report})
+	return report
+}
+
+func (r *Runner) notify(ctx context.Context, report notifier.Report) {
+	for _, n := range r.notifiers {
+		ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		err := n.Notify(ctx, report)
+		cancel()
+		if err != nil {
+			log.Printf("failed to notify for %s: %v", report.Repo, err)
+		}
+	}
+}
+
+func (r *Runner) notifyDigest(ctx context.Context, reports []notifier.Report) {
+	for _, n := range r.digestNotifiers {
+		ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		err := n.NotifyDigest(ctx, reports)
+		cancel()
+		if err != nil {
+			log.Printf("failed to send digest notification: %v", err)
+		}
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// Latest returns the pending updates found by the last successful run of
+// repo, falling back to the state store for runs of previous processes.
+// platform and endpoint select a discovery source and default to the
+// top-level settings.
+func (r *Runner) Latest(platform, endpoint, repo string) (Result, bool) {
+	if platform == "" {
+		platform = r.cfg.Platform
+		if endpoint == "" {
+			endpoint = r.cfg.Endpoint
+		}
+	}
+	key := r.key(platform, endpoint, repo)
+
+	r.mu.Lock()
+	result, ok := r.latest[key]
+	r.mu.Unlock()
+	if ok {
+		return result, true
+	}
+
+	if r.store != nil {
+		if rs, ok := r.store.Get(key); ok {
+			return Result{Repo: repo, UpdatedAt: rs.UpdatedAt, Updates: rs.Updates, Problems: rs.Problems}, true
+		}
+	}
+
+	return Result{}, false
+}
@@ -0,0 +1,152 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{expr: "* * * * *"},
+		{expr: "0 9 * * MON-FRI"},
+		{expr: "*/15 0-6,22 1,15 jan-jun ?"},
+		{expr: "5/10 * * * *"},
+		{expr: "0 0 * * 7"},
+		{expr: "@daily"},
+		{expr: " @Hourly "},
+		{expr: "* * * *", wantErr: true},
+		{expr: "* * * * * *", wantErr: true},
+		{expr: "60 * * * *", wantErr: true},
+		{expr: "* 24 * * *", wantErr: true},
+		{expr: "* * 0 * *", wantErr: true},
+		{expr: "* * * 13 *", wantErr: true},
+		{expr: "* * * * 8", wantErr: true},
+		{expr: "*/0 * * * *", wantErr: true},
+		{expr: "5-1 * * * *", wantErr: true},
+		{expr: "* * * FOO *", wantErr: true},
+		{expr: "@never", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	// Havana starts daylight saving time at midnight, so 2026-03-08 has no 00:00.
+	havana := mustLoadLocation(t, "America/Havana")
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "every 15 minutes",
+			expr: "*/15 * * * *",
+			from: time.Date(2026, 1, 1, 10, 7, 30, 0, time.UTC),
+			want: time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "strictly after",
+			expr: "0 9 * * *",
+			from: time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekdays skip the weekend",
+			expr: "0 9 * * MON-FRI",
+			from: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), // Friday
+			want: time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "sunday as 7",
+			expr: "0 0 * * 7",
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), // Thursday
+			want: time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "restricted day fields match either",
+			expr: "0 0 13 * FRI",
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leap day",
+			expr: "0 0 29 2 *",
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "time in spring forward gap is skipped",
+			expr: "30 2 * * *",
+			from: time.Date(2026, 3, 7, 23, 0, 0, 0, newYork),
+			want: time.Date(2026, 3, 9, 2, 30, 0, 0, newYork),
+		},
+		{
+			name: "hour after spring forward gap",
+			expr: "0 3 * * *",
+			from: time.Date(2026, 3, 8, 1, 17, 0, 0, newYork),
+			want: time.Date(2026, 3, 8, 3, 0, 0, 0, newYork),
+		},
+		{
+			name: "fall back",
+			expr: "30 1 * * *",
+			from: time.Date(2026, 10, 31, 23, 0, 0, 0, newYork),
+			want: time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+		},
+		{
+			name: "midnight in spring forward gap is skipped",
+			expr: "0 0 * * *",
+			from: time.Date(2026, 3, 7, 12, 0, 0, 0, havana),
+			want: time.Date(2026, 3, 9, 0, 0, 0, 0, havana),
+		},
+		{
+			name: "day step across a day starting in a gap",
+			expr: "0 9 * * MON-FRI",
+			from: time.Date(2026, 3, 7, 10, 0, 0, 0, havana), // Saturday
+			want: time.Date(2026, 3, 9, 9, 0, 0, 0, havana),
+		},
+		{
+			name: "month step across a day starting in a gap",
+			expr: "0 9 1 4 *",
+			from: time.Date(2026, 3, 7, 10, 0, 0, 0, havana),
+			want: time.Date(2026, 4, 1, 9, 0, 0, 0, havana),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+
+			got := s.Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+			if got.Location() != tt.from.Location() {
+				t.Errorf("Next(%s) location = %s, want %s", tt.from, got.Location(), tt.from.Location())
+			}
+		})
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
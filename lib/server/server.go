@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/notifier"
+	"github.com/snowmerak/renovates/lib/renovate"
+	"github.com/snowmerak/renovates/lib/runner"
+)
+
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+
+	// Finished runs beyond this count are forgotten, oldest first.
+	maxRuns = 100
+)
+
+type Run struct {
+	ID         string            `json:"id"`
+	Status     string            `json:"status"`
+	Repos      []string          `json:"repos,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	StartedAt  *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+	Results    []notifier.Report `json:"results,omitempty"`
+}
+
+type createRunRequest struct {
+	Repos []string `json:"repos"`
+}
+
+// Server exposes an HTTP API to trigger Renovate runs and query their results.
+// Runs are queued and executed one at a time.
+type Server struct {
+	runner *runner.Runner
+	token  string
+
+	// baseCtx is cancelled when the server shuts down to abort queued runs
+	baseCtx context.Context
+	cancel  context.CancelFunc
+	runMu   sync.Mutex
+
+	mu    sync.Mutex
+	runs  map[string]*Run
+	order []string
+	wg    sync.WaitGroup
+}
+
+func New(r *runner.Runner, token string) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		runner:  r,
+		token:   token,
+		baseCtx: ctx,
+		cancel:  cancel,
+		runs:    make(map[string]*Run),
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.handleCreateRun)
+	mux.HandleFunc("GET /runs/{id}", s.handleGetRun)
+	mux.HandleFunc("GET /repos/{owner}/{name}/updates", s.handleGetUpdates)
+	return s.authenticate(mux)
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled, then waits
+// for in-flight runs to finish.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		s.cancel()
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+
+	s.cancel()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+
+	expected := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleCreateRun(w http.ResponseWriter, r *http.Request) {
+	var req createRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	for _, repo := range req.Repos {
+		if err := renovate.ValidateRepo(repo); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	run := &Run{
+		ID:        newRunID(),
+		Status:    RunQueued,
+		Repos:     req.Repos,
+		CreatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+	s.pruneLocked()
+	snapshot := *run
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.execute(run.ID)
+
+	w.Header().Set("Location", "/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	run, ok := s.runs[r.PathValue("id")]
+	var snapshot Run
+	if ok {
+		snapshot = *run
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *Server) handleGetUpdates(w http.ResponseWriter, r *http.Request) {
+	repo := r.PathValue("owner") + "/" + r.PathValue("name")
+
+	// Repositories of other discovery sources are selected with
+	// ?platform=gitlab&endpoint=https://gitlab.example.com/api/v4
+	query := r.URL.Query()
+	result, ok := s.runner.Latest(query.Get("platform"), query.Get("endpoint"), repo)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no results for repository")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) execute(id string) {
+	defer s.wg.Done()
+
+	// Only one run at a time so repositories are never scanned concurrently
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	ctx := s.baseCtx
+	if ctx.Err() != nil {
+		s.finish(id, nil, ctx.Err())
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	run := s.runs[id]
+	run.Status = RunRunning
+	run.StartedAt = &now
+	repos := run.Repos
+	s.mu.Unlock()
+
+	var targets []runner.Target
+	if len(repos) == 0 {
+		var err error
+		targets, err = s.runner.Discover(ctx)
+		if err != nil {
+			s.finish(id, nil, err)
+			return
+		}
+
+		repos = make([]string, 0, len(targets))
+		for _, t := range targets {
+			repos = append(repos, t.Repo)
+		}
+		s.mu.Lock()
+		run.Repos = repos
+		s.mu.Unlock()
+	} else {
+		targets = s.runner.Targets(repos)
+	}
+
+	reports := s.runner.RunWithID(ctx, id, targets)
+	s.finish(id, reports, ctx.Err())
+}
+
+func (s *Server) finish(id string, reports []notifier.Report, err error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[id]
+	if !ok {
+		return
+	}
+	run.FinishedAt = &now
+	run.Results = reports
+	run.Status = RunCompleted
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+		log.Printf("run %s failed: %v", id, err)
+	}
+}
+
+// pruneLocked drops the oldest finished runs once more than maxRuns are kept.
+func (s *Server) pruneLocked() {
+	for len(s.order) > maxRuns {
+		removed := false
+		for i, id := range s.order {
+			if st := s.runs[id].Status; st == RunCompleted || st == RunFailed {
+				delete(s.runs, id)
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			return
+		}
+	}
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,167 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+)
+
+type RepoState struct {
+	UpdatedAt time.Time             `json:"updatedAt"`
+	Updates   []renovate.UpdateInfo `json:"updates"`
+	Problems  []renovate.Problem    `json:"problems,omitempty"`
+}
+
+type fileData struct {
+	Repos map[string]RepoState `json:"repos"`
+}
+
+// Store keeps the updates reported for each repository by the previous run
+// in a JSON file.
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	data fileData
+}
+
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		data: fileData{Repos: make(map[string]RepoState)},
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&s.data); err != nil {
+		return nil, fmt.Errorf("failed to decode state file: %w", err)
+	}
+	if s.data.Repos == nil {
+		s.data.Repos = make(map[string]RepoState)
+	}
+
+	return s, nil
+}
+
+// Changes is the difference between a run of a repository and the previous one.
+type Changes struct {
+	Added    []renovate.UpdateInfo
+	Resolved []renovate.UpdateInfo
+	// NewProblems holds the problems that were not present in the previous run.
+	NewProblems []renovate.Problem
+}
+
+// Diff compares a run of repo with the previous one without recording anything.
+func (s *Store) Diff(repo string, updates []renovate.UpdateInfo, problems []renovate.Problem) Changes {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.diffLocked(repo, updates, problems)
+}
+
+// Record stores the updates and problems of the current run for repo and
+// compares them with the previous run.
+func (s *Store) Record(repo string, updates []renovate.UpdateInfo, problems []renovate.Problem) (Changes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := s.diffLocked(repo, updates, problems)
+
+	s.data.Repos[repo] = RepoState{
+		UpdatedAt: time.Now(),
+		Updates:   updates,
+		Problems:  problems,
+	}
+
+	if err := s.save(); err != nil {
+		return Changes{}, err
+	}
+
+	return changes, nil
+}
+
+func (s *Store) diffLocked(repo string, updates []renovate.UpdateInfo, problems []renovate.Problem) Changes {
+	var changes Changes
+	prev := s.data.Repos[repo]
+
+	previous := make(map[string]bool)
+	for _, u := range prev.Updates {
+		previous[u.Key()] = true
+	}
+
+	current := make(map[string]bool)
+	for _, u := range updates {
+		current[u.Key()] = true
+		if !previous[u.Key()] {
+			changes.Added = append(changes.Added, u)
+		}
+	}
+
+	for _, u := range prev.Updates {
+		if !current[u.Key()] {
+			changes.Resolved = append(changes.Resolved, u)
+		}
+	}
+
+	previousProblems := make(map[string]bool)
+	for _, p := range prev.Problems {
+		previousProblems[p.Key()] = true
+	}
+	for _, p := range problems {
+		if !previousProblems[p.Key()] {
+			changes.NewProblems = append(changes.NewProblems, p)
+		}
+	}
+
+	return changes
+}
+
+// Get returns the updates recorded for repo by the last run.
+func (s *Store) Get(repo string) (RepoState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.data.Repos[repo]
+	return rs, ok
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated state
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
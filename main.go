@@ -5,84 +5,116 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"sync"
-
-	"github.com/snowmerak/renovates/lib/discovery"
-	"github.com/snowmerak/renovates/lib/notifier"
 	"github.com/snowmerak/renovates/lib/renovate"
+	"github.com/snowmerak/renovates/lib/runner"
+	"github.com/snowmerak/renovates/lib/server"
 )
 
 func main() {
+	// parse only needs the config to notify
+	if len(os.Args) > 1 && os.Args[1] == "parse" {
+		if err := runParse(os.Args[2:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
 	cfg, err := renovate.LoadConfig("config.toml")
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	var repos []string
+	r, err := runner.New(cfg)
+	if err != nil {
+		log.Fatalf("failed to create runner: %v", err)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
 	if len(os.Args) > 1 {
-		repos = []string{os.Args[1]}
-	} else if cfg.Discovery.Enabled {
-		fmt.Println("Discovering repositories...")
-		d, err := discovery.NewDiscoverer(cfg)
+		switch os.Args[1] {
+		case "daemon":
+			if err := runDaemon(ctx, cfg, r); err != nil {
+				log.Fatalf("daemon stopped: %v", err)
+			}
+			return
+		case "serve":
+			addr := cfg.Server.Addr
+			if addr == "" {
+				addr = "127.0.0.1:8080"
+			}
+			fmt.Printf("Serving API on %s\n", addr)
+			if err := server.New(r, cfg.Server.Token).ListenAndServe(ctx, addr); err != nil {
+				log.Fatalf("server stopped: %v", err)
+			}
+			return
+		}
+	}
+
+	var targets []runner.Target
+	if len(os.Args) > 1 {
+		targets, err = argTargets(r, os.Args[1:])
 		if err != nil {
-			log.Fatalf("failed to create discoverer: %v", err)
+			log.Fatalf("%v", err)
 		}
-		repos, err = d.ListRepositories(context.Background())
+	} else if cfg.Discovery.Enabled {
+		fmt.Println("Discovering repositories...")
+		targets, err = r.Discover(ctx)
 		if err != nil {
-			log.Fatalf("failed to discover repositories: %v", err)
+			log.Fatalf("%v", err)
 		}
-		fmt.Printf("Found %d repositories: %v\n", len(repos), repos)
+		fmt.Printf("Found %d repositories: %v\n", len(targets), targets)
 	} else {
-		fmt.Println("Usage: renovates <repo> or enable discovery in config")
+		fmt.Println("Usage: renovates <repo>, renovates --repos-file <file>, renovates - (repositories from stdin), renovates daemon, renovates serve, renovates parse <log>, or enable discovery in config")
 		os.Exit(1)
 	}
 
-	concurrency := cfg.Concurrency
-	if concurrency < 1 {
-		concurrency = 1
-	}
-	sem := make(chan struct{}, concurrency)
-	var wg sync.WaitGroup
-
-	for _, repo := range repos {
-		wg.Add(1)
-		sem <- struct{}{} // Acquire semaphore
+	r.Run(ctx, targets)
+}
 
-		go func(r string) {
-			defer wg.Done()
-			defer func() { <-sem }() // Release semaphore
+// signalContext returns a context that is cancelled on the first SIGINT or
+// SIGTERM. The handler is then removed, so a second signal terminates the
+// process instead of waiting for a graceful shutdown.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
 
-			fmt.Printf("Running renovate for %s...\n", r)
-			output, err := cfg.Run(context.Background(), r)
-			if err != nil {
-				log.Printf("failed to run renovate for %s: %v", r, err)
-				return
+// argTargets returns the repositories given on the command line: a single
+// repository, a repository list file, or "-" to read the list from stdin.
+func argTargets(r *runner.Runner, args []string) ([]runner.Target, error) {
+	switch {
+	case args[0] == "-":
+		return r.ReadTargets(os.Stdin)
+	case args[0] == "--repos-file" || strings.HasPrefix(args[0], "--repos-file="):
+		path, ok := strings.CutPrefix(args[0], "--repos-file=")
+		if !ok {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("--repos-file requires a path")
 			}
+			path = args[1]
+		}
 
-			updates := renovate.ParseUpdates(output)
-
-			var notifiers []notifier.Notifier
-			for _, n := range cfg.Notifiers {
-				switch n.Type {
-				case "stdout":
-					notifiers = append(notifiers, notifier.NewStdoutNotifier())
-				case "webhook":
-					notifiers = append(notifiers, notifier.NewWebhookNotifier(n.URL))
-				case "teams":
-					notifiers = append(notifiers, notifier.NewTeamsNotifier(n.URL))
-				case "telegram":
-					notifiers = append(notifiers, notifier.NewTelegramNotifier(n.Token, n.ChatID))
-				}
-			}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open repository list: %w", err)
+		}
+		defer f.Close()
 
-			for _, n := range notifiers {
-				if err := n.Notify(context.Background(), r, updates); err != nil {
-					log.Printf("failed to notify for %s: %v", r, err)
-				}
-			}
-		}(repo)
+		return r.ReadTargets(f)
+	default:
+		if err := renovate.ValidateRepo(args[0]); err != nil {
+			return nil, err
+		}
+		return r.Targets([]string{args[0]}), nil
 	}
-
-	wg.Wait()
 }
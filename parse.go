@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/snowmerak/renovates/lib/renovate"
+	"github.com/snowmerak/renovates/lib/runner"
+)
+
+// runParse parses a saved Renovate JSON log instead of running Renovate, and
+// prints the result or sends it to the configured notifiers.
+func runParse(args []string) error {
+	fs := flag.NewFlagSet("parse", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	notify := fs.Bool("notify", false, "send the result to the notifiers in config.toml instead of printing it")
+	repo := fs.String("repo", "", "repository the log belongs to, required with --notify")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: renovates parse [--format table|json] [--notify --repo <repo>] [<log file>|-]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *notify && *repo == "" {
+		return fmt.Errorf("--notify requires --repo")
+	}
+	if !*notify && *format != "table" && *format != "json" {
+		return fmt.Errorf("unsupported format %q, expected table or json", *format)
+	}
+
+	in := io.Reader(os.Stdin)
+	if path := fs.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open log: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	rd, err := decompress(in)
+	if err != nil {
+		return err
+	}
+	result, err := renovate.ParseReader(rd)
+	if err != nil {
+		return err
+	}
+
+	if *notify {
+		cfg, err := renovate.LoadConfig("config.toml")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		r, err := runner.NewNotifier(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create notifiers: %w", err)
+		}
+
+		ctx, stop := signalContext()
+		defer stop()
+
+		r.Notify(ctx, *repo, result)
+		return nil
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	return printTable(os.Stdout, result)
+}
+
+// decompress transparently reads gzip-compressed logs, as saved with
+// [logs] gzip = true.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return br, nil
+	}
+
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compressed log: %w", err)
+	}
+	return zr, nil
+}
+
+func printTable(w io.Writer, result renovate.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if len(result.Updates) == 0 {
+		fmt.Fprintln(tw, "No updates found.")
+	} else {
+		fmt.Fprintln(tw, "PACKAGE\tCURRENT\tNEW\tTYPE\tFILE\tSECURITY")
+		for _, u := range result.Updates {
+			security := ""
+			if u.IsVulnerabilityAlert {
+				security = strings.Join(append([]string{u.VulnerabilitySeverity}, u.Vulnerabilities...), " ")
+				security = strings.TrimSpace(security)
+				if security == "" {
+					security = "yes"
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", u.DepName, u.CurrentVersion, u.NewVersion, u.UpdateType, u.PackageFile, security)
+		}
+	}
+
+	if len(result.Problems) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "LEVEL\tDEPENDENCY\tMESSAGE")
+		for _, p := range result.Problems {
+			msg := p.Message
+			if p.Error != "" {
+				msg += ": " + p.Error
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Level, p.DepName, msg)
+		}
+	}
+
+	return tw.Flush()
+}